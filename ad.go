@@ -0,0 +1,266 @@
+package ldif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Profile selects the conventions of the LDIF tool of a directory server,
+// which the parser and Marshal follow in addition to RFC 2849.
+type Profile int
+
+// The profiles.
+//
+// ProfileDefault follows RFC 2849 only.
+//
+// ProfileActiveDirectory follows the conventions of ldifde, the LDIF tool
+// of Active Directory:
+//   - the parser reads UTF-16LE input starting with a byte order mark, as
+//     written by "ldifde -u"
+//   - Marshal writes content records as "changetype: add" records and ends
+//     the lines with CR LF
+//   - Marshal writes DNs with line breaks or other control characters, like
+//     the DNs of deleted objects ("CN=foo\0ADEL:..."), with escapes of RFC
+//     4514 instead of base64, other DNs are written as they are
+//
+// The binary values of ldifde, e.g. of objectGUID, objectSid and
+// unicodePwd, are base64 encoded as usual, see ObjectGUIDString,
+// ObjectSIDString, EncodeUnicodePwd and DecodeUnicodePwd for their content.
+const (
+	ProfileDefault Profile = iota
+	ProfileActiveDirectory
+)
+
+// String returns the name of the profile.
+func (p Profile) String() string {
+	switch p {
+	case ProfileDefault:
+		return "default"
+	case ProfileActiveDirectory:
+		return "ad"
+	}
+	return fmt.Sprintf("Profile(%d)", int(p))
+}
+
+var utf16BOM = []byte{0xff, 0xfe}
+
+// utf16Reader returns a reader which converts the UTF-16LE input of r to
+// UTF-8 if it starts with a byte order mark, other input is passed through.
+func utf16Reader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(utf16BOM)); !bytes.Equal(head, utf16BOM) {
+		return br
+	}
+	br.Discard(len(utf16BOM))
+	return &utf16Decoder{r: br}
+}
+
+// utf16Decoder decodes UTF-16LE to UTF-8, invalid surrogates become
+// U+FFFD.
+type utf16Decoder struct {
+	r   *bufio.Reader
+	out []byte // decoded bytes not yet returned
+	err error  // the read error, returned after out
+}
+
+func (d *utf16Decoder) unit() (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(d.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("odd number of bytes in UTF-16 input")
+		}
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b[:]), nil
+}
+
+func (d *utf16Decoder) Read(p []byte) (int, error) {
+	for len(d.out) < len(p) && d.err == nil {
+		u, err := d.unit()
+		if err != nil {
+			d.err = err
+			break
+		}
+		r := rune(u)
+		if utf16.IsSurrogate(r) {
+			// a high surrogate must be followed by a low one
+			if next, err := d.r.Peek(2); err == nil {
+				low := rune(binary.LittleEndian.Uint16(next))
+				if dec := utf16.DecodeRune(r, low); dec != utf8.RuneError {
+					d.r.Discard(2)
+					r = dec
+				} else {
+					r = utf8.RuneError
+				}
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		d.out = utf8.AppendRune(d.out, r)
+	}
+	if len(d.out) == 0 && len(p) > 0 {
+		return 0, d.err
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// crlfWriter replaces the line feeds written to it with CR LF.
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, lf)
+		if i < 0 {
+			m, err := c.w.Write(p)
+			return n + m, err
+		}
+		if _, err := c.w.Write(p[:i]); err != nil {
+			return n, err
+		}
+		if _, err := c.w.Write([]byte{cr, lf}); err != nil {
+			return n, err
+		}
+		n += i + 1
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// adRecord returns the record as ldifde writes it: content records as add
+// records, DNs with control characters escaped.
+func adRecord(e *Entry) *Entry {
+	switch {
+	case e.Entry != nil:
+		add := ldap.NewAddRequest(adDN(e.Entry.DN), nil)
+		for _, a := range e.Entry.Attributes {
+			add.Attribute(a.Name, a.Values)
+		}
+		return &Entry{Add: add}
+	case e.Add != nil:
+		add := *e.Add
+		add.DN = adDN(add.DN)
+		return &Entry{Add: &add}
+	case e.Del != nil:
+		del := *e.Del
+		del.DN = adDN(del.DN)
+		return &Entry{Del: &del}
+	case e.Modify != nil:
+		modify := *e.Modify
+		modify.DN = adDN(modify.DN)
+		return &Entry{Modify: &modify}
+	case e.ModifyDN != nil:
+		modifyDN := *e.ModifyDN
+		modifyDN.DN = adDN(modifyDN.DN)
+		return &Entry{ModifyDN: &modifyDN}
+	}
+	return e
+}
+
+// adDN escapes the control characters in the values of the DN as "\XX", a
+// DN without control characters or which cannot be parsed is returned as
+// it is.
+func adDN(dn string) string {
+	if !strings.ContainsFunc(dn, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+		return dn
+	}
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return dn
+	}
+	rdns := make([]string, len(parsed.RDNs))
+	for i, rdn := range parsed.RDNs {
+		avas := make([]string, len(rdn.Attributes))
+		for j, ava := range rdn.Attributes {
+			var b strings.Builder
+			for _, c := range []byte(escapeDNValue(ava.Value)) {
+				if c < ' ' || c == 0x7f {
+					fmt.Fprintf(&b, "\\%02X", c)
+					continue
+				}
+				b.WriteByte(c)
+			}
+			avas[j] = ava.Type + "=" + b.String()
+		}
+		rdns[i] = strings.Join(avas, "+")
+	}
+	return strings.Join(rdns, ",")
+}
+
+// EncodeUnicodePwd returns the value of the unicodePwd attribute for the
+// password: the password in double quotes, encoded as UTF-16LE.
+func EncodeUnicodePwd(password string) string {
+	units := utf16.Encode([]rune(`"` + password + `"`))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return string(b)
+}
+
+// DecodeUnicodePwd returns the password of a unicodePwd value, it is the
+// reverse of EncodeUnicodePwd.
+func DecodeUnicodePwd(value string) (string, error) {
+	if len(value)%2 != 0 {
+		return "", errors.New("unicodePwd: odd number of bytes")
+	}
+	units := make([]uint16, len(value)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16([]byte(value[2*i:]))
+	}
+	password := string(utf16.Decode(units))
+	if len(password) < 2 || password[0] != '"' || password[len(password)-1] != '"' {
+		return "", errors.New("unicodePwd: password is not quoted")
+	}
+	return password[1 : len(password)-1], nil
+}
+
+// ObjectGUIDString returns the objectGUID value in the string form of
+// Active Directory, e.g. "b9a8b5f4-3d2e-4c1a-9f8e-7d6c5b4a3210". The first
+// three groups are stored little-endian.
+func ObjectGUIDString(value string) (string, error) {
+	b := []byte(value)
+	if len(b) != 16 {
+		return "", fmt.Errorf("objectGUID: invalid length %d", len(b))
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16]), nil
+}
+
+// ObjectSIDString returns the objectSid value (or another binary security
+// identifier) in the string form of Active Directory, e.g.
+// "S-1-5-21-1004336348-1177238915-682003330-512".
+func ObjectSIDString(value string) (string, error) {
+	b := []byte(value)
+	if len(b) < 8 || len(b) != 8+4*int(b[1]) {
+		return "", fmt.Errorf("objectSid: invalid length %d", len(b))
+	}
+	// the identifier authority is 48 bit big-endian
+	authority := uint64(0)
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	var s strings.Builder
+	s.WriteString("S-" + strconv.Itoa(int(b[0])) + "-" + strconv.FormatUint(authority, 10))
+	for i := 8; i < len(b); i += 4 {
+		s.WriteString("-" + strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b[i:])), 10))
+	}
+	return s.String(), nil
+}
@@ -0,0 +1,142 @@
+package ldif_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var ldifdeExport = "dn: CN=Doe\\, John,OU=Users,DC=example,DC=com\r\n" +
+	"changetype: add\r\n" +
+	"objectClass: user\r\n" +
+	"cn: Doe, John\r\n" +
+	"objectGUID:: 9LWouS49Gkyfjn1sW0oyEA==\r\n" +
+	"objectSid:: AQUAAAAAAAUVAAAA3MPdO4OTKEaC+6YoAAIAAA==\r\n" +
+	"\r\n" +
+	"dn: CN=Group,OU=Users,DC=example,DC=com\r\n" +
+	"changetype: modify\r\n" +
+	"replace: description\r\n" +
+	"description: Jörg's group\r\n" +
+	"-\r\n" +
+	"\r\n"
+
+// utf16LE returns s as UTF-16LE with a byte order mark, like "ldifde -u".
+func utf16LE(s string) string {
+	units := utf16.Encode([]rune(s))
+	b := []byte{0xff, 0xfe}
+	for _, u := range units {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return string(b)
+}
+
+func TestProfileActiveDirectory(t *testing.T) {
+	for name, input := range map[string]string{"utf-8": ldifdeExport, "utf-16": utf16LE(ldifdeExport)} {
+		t.Run(name, func(t *testing.T) {
+			l := &ldif.LDIF{Profile: ldif.ProfileActiveDirectory}
+			if err := ldif.Unmarshal(strings.NewReader(input), l); err != nil {
+				t.Fatal(err)
+			}
+			if len(l.Entries) != 2 || l.Entries[0].Add == nil || l.Entries[1].Modify == nil {
+				t.Fatalf("unexpected records: %+v", l.Entries)
+			}
+			if v := l.Entries[1].Modify.Changes[0].Modification.Vals[0]; v != "Jörg's group" {
+				t.Errorf("got description %q", v)
+			}
+			var guid, sid string
+			for _, a := range l.Entries[0].Add.Attributes {
+				switch a.Type {
+				case "objectGUID":
+					guid, _ = ldif.ObjectGUIDString(a.Vals[0])
+				case "objectSid":
+					sid, _ = ldif.ObjectSIDString(a.Vals[0])
+				}
+			}
+			if guid != "b9a8b5f4-3d2e-4c1a-9f8e-7d6c5b4a3210" {
+				t.Errorf("got objectGUID %q", guid)
+			}
+			if sid != "S-1-5-21-1004389340-1177064323-682032002-512" {
+				t.Errorf("got objectSid %q", sid)
+			}
+		})
+	}
+}
+
+func TestMarshalProfileActiveDirectory(t *testing.T) {
+	entry := &ldap.Entry{DN: "CN=foo\nDEL:1234,CN=Deleted Objects,DC=example,DC=com", Attributes: []*ldap.EntryAttribute{
+		ldap.NewEntryAttribute("cn", []string{"foo"}),
+		ldap.NewEntryAttribute("unicodePwd", []string{ldif.EncodeUnicodePwd("Secret1!")}),
+	}}
+	s, err := ldif.Marshal(&ldif.LDIF{Entries: []*ldif.Entry{{Entry: entry}}, Profile: ldif.ProfileActiveDirectory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "dn: CN=foo\\0ADEL:1234,CN=Deleted Objects,DC=example,DC=com\r\n" +
+		"changetype: add\r\n" +
+		"cn: foo\r\n" +
+		"unicodePwd:: IgBTAGUAYwByAGUAdAAxACEAIgA=\r\n" +
+		"\r\n"
+	if s != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, s)
+	}
+
+	l := &ldif.LDIF{Profile: ldif.ProfileActiveDirectory}
+	if err := ldif.Unmarshal(strings.NewReader(s), l); err != nil {
+		t.Fatal(err)
+	}
+	if dn := l.Entries[0].DN(); dn != "CN=foo\\0ADEL:1234,CN=Deleted Objects,DC=example,DC=com" {
+		t.Errorf("got DN %q", dn)
+	}
+	var password string
+	for _, a := range l.Entries[0].Add.Attributes {
+		if a.Type == "unicodePwd" {
+			password, err = ldif.DecodeUnicodePwd(a.Vals[0])
+		}
+	}
+	if err != nil || password != "Secret1!" {
+		t.Errorf("got password %q, %v", password, err)
+	}
+}
+
+func TestADValueErrors(t *testing.T) {
+	if _, err := ldif.DecodeUnicodePwd(ldif.EncodeUnicodePwd("x")[2:]); err == nil {
+		t.Error("unquoted unicodePwd: no error")
+	}
+	if _, err := ldif.DecodeUnicodePwd("abc"); err == nil {
+		t.Error("odd unicodePwd: no error")
+	}
+	if _, err := ldif.ObjectGUIDString("short"); err == nil {
+		t.Error("short objectGUID: no error")
+	}
+	if _, err := ldif.ObjectSIDString("\x01\x05\x00\x00\x00\x00\x00\x05"); err == nil {
+		t.Error("short objectSid: no error")
+	}
+}
+
+func TestProfileActiveDirectoryReadError(t *testing.T) {
+	// the records decoded before a read error are returned, then the error
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader(utf16LE(ldifdeExport)), iotest.ErrReader(readErr))
+	var records []*ldif.Entry
+	var err error
+	for e, perr := range ldif.UnmarshalEntries(r, &ldif.LDIF{Profile: ldif.ProfileActiveDirectory}) {
+		if perr != nil {
+			err = perr
+			break
+		}
+		records = append(records, e)
+	}
+	if len(records) != 2 {
+		t.Errorf("got %d records before the error, expected 2", len(records))
+	}
+	if !errors.Is(err, readErr) {
+		t.Errorf("got error %v, expected %v", err, readErr)
+	}
+}
@@ -0,0 +1,325 @@
+package ldif
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// An AnonymizeStrategy says how the values of an attribute are anonymized.
+type AnonymizeStrategy int
+
+const (
+	// AnonymizeDrop removes the attribute.
+	AnonymizeDrop AnonymizeStrategy = iota + 1
+	// AnonymizeFixed replaces each value with the fixed AnonymizeRule.Value
+	// (default "redacted").
+	AnonymizeFixed
+	// AnonymizeHash replaces each value with its keyed hash (HMAC-SHA256,
+	// 16 hex digits), so equal values stay equal.
+	AnonymizeHash
+	// AnonymizeFakeName replaces each value with a made-up name chosen by
+	// the keyed hash of the value: a given name for givenName, a surname
+	// for sn and surname, both for all other attributes.
+	AnonymizeFakeName
+	// AnonymizeMask replaces letters with letters and digits with digits,
+	// chosen by the keyed hash of the value, and keeps all other
+	// characters, so phone numbers keep their format. The domain of mail
+	// addresses is kept.
+	AnonymizeMask
+)
+
+// An AnonymizeRule is the strategy for an attribute, without a Strategy
+// the values are hashed.
+type AnonymizeRule struct {
+	Strategy AnonymizeStrategy
+	// Value is the replacement of AnonymizeFixed.
+	Value string
+}
+
+// An Anonymizer replaces the values of personal attributes in content and
+// change records. Its Anonymize method is a Transform, the rules must not
+// be changed after its first call.
+//
+// The hash, fake names and masks depend on the Key and on the value only,
+// compared case-insensitively and with repeated spaces ignored, so the same
+// value is always replaced the same way. This keeps references between the
+// records intact: the values of the attributes with a rule are replaced in
+// the DNs of the records (and newrdn and newsuperior), and in the values of
+// DNAttributes, too. As a value must not be dropped or become equal to
+// other values in a DN, the drop and fixed strategies use the hash in DNs,
+// so naming attributes like uid or cn should use the hash, fake name or
+// mask strategies.
+type Anonymizer struct {
+	// Key is the secret key of the hash, it is required.
+	Key []byte
+	// Rules are the strategies by attribute name (case-insensitive),
+	// attributes without a rule are kept.
+	Rules map[string]AnonymizeRule
+	// DNAttributes are the attributes with DN values, DNAttributes (the
+	// package variable) if nil. A rule for such an attribute replaces the
+	// rewriting of its DNs.
+	DNAttributes []string
+
+	once    sync.Once
+	rules   map[string]AnonymizeRule
+	dnAttrs map[string]bool
+}
+
+var errNoKey = errors.New("anonymizer requires a key")
+
+func (a *Anonymizer) init() error {
+	if len(a.Key) == 0 {
+		return errNoKey
+	}
+	a.once.Do(func() {
+		a.rules = make(map[string]AnonymizeRule, len(a.Rules))
+		for name, rule := range a.Rules {
+			a.rules[strings.ToLower(name)] = rule
+		}
+		dnAttrs := a.DNAttributes
+		if dnAttrs == nil {
+			dnAttrs = DNAttributes
+		}
+		a.dnAttrs = make(map[string]bool, len(dnAttrs))
+		for _, name := range dnAttrs {
+			a.dnAttrs[strings.ToLower(name)] = true
+		}
+	})
+	return nil
+}
+
+func (a *Anonymizer) rule(attr string) (AnonymizeRule, bool) {
+	name, _, _ := strings.Cut(attr, ";")
+	rule, ok := a.rules[strings.ToLower(name)]
+	return rule, ok
+}
+
+// Anonymize replaces the values of the record as configured by the rules,
+// the record is changed in place.
+func (a *Anonymizer) Anonymize(e *Entry) (*Entry, error) {
+	if err := a.init(); err != nil {
+		return nil, err
+	}
+	var err error
+	switch {
+	case e.Entry != nil:
+		var attrs []*ldap.EntryAttribute
+		for _, attr := range e.Entry.Attributes {
+			if values, keep := a.values(attr.Name, attr.Values); keep {
+				setValues(attr, values)
+				attrs = append(attrs, attr)
+			}
+		}
+		e.Entry.Attributes = attrs
+		e.Entry.DN, err = a.dn(e.Entry.DN)
+	case e.Add != nil:
+		var attrs []ldap.Attribute
+		for _, attr := range e.Add.Attributes {
+			if values, keep := a.values(attr.Type, attr.Vals); keep {
+				attr.Vals = values
+				attrs = append(attrs, attr)
+			}
+		}
+		e.Add.Attributes = attrs
+		e.Add.DN, err = a.dn(e.Add.DN)
+	case e.Del != nil:
+		e.Del.DN, err = a.dn(e.Del.DN)
+	case e.Modify != nil:
+		var changes []ldap.Change
+		for _, c := range e.Modify.Changes {
+			if values, keep := a.values(c.Modification.Type, c.Modification.Vals); keep {
+				if len(c.Modification.Vals) == 0 {
+					values = nil
+				}
+				c.Modification.Vals = values
+				changes = append(changes, c)
+			}
+		}
+		e.Modify.Changes = changes
+		e.Modify.DN, err = a.dn(e.Modify.DN)
+	case e.ModifyDN != nil:
+		if e.ModifyDN.NewRDN, err = a.dn(e.ModifyDN.NewRDN); err != nil {
+			return nil, err
+		}
+		if e.ModifyDN.NewSuperior != "" {
+			if e.ModifyDN.NewSuperior, err = a.dn(e.ModifyDN.NewSuperior); err != nil {
+				return nil, err
+			}
+		}
+		e.ModifyDN.DN, err = a.dn(e.ModifyDN.DN)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// values returns the anonymized values of the attribute and false if the
+// attribute is dropped.
+func (a *Anonymizer) values(attr string, values []string) ([]string, bool) {
+	rule, ok := a.rule(attr)
+	if !ok {
+		name, _, _ := strings.Cut(attr, ";")
+		if !a.dnAttrs[strings.ToLower(name)] {
+			return values, true
+		}
+		result := make([]string, len(values))
+		for i, v := range values {
+			// values which are no DNs are kept
+			if result[i], ok = a.dnValue(v); !ok {
+				result[i] = v
+			}
+		}
+		return result, true
+	}
+	if rule.Strategy == AnonymizeDrop {
+		return nil, false
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = a.value(attr, rule, v)
+	}
+	return result, true
+}
+
+func (a *Anonymizer) value(attr string, rule AnonymizeRule, v string) string {
+	switch rule.Strategy {
+	case AnonymizeFixed:
+		if rule.Value == "" {
+			return "redacted"
+		}
+		return rule.Value
+	case AnonymizeFakeName:
+		return a.fakeName(attr, v)
+	case AnonymizeMask:
+		return a.mask(v)
+	}
+	return a.hash(v)
+}
+
+// dn returns the DN with the anonymized values of its RDNs, a DN without
+// such values is kept as it is.
+func (a *Anonymizer) dn(dn string) (string, error) {
+	if dn == "" {
+		return dn, nil
+	}
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %s", ErrInvalidDN, dn, err)
+	}
+	return a.anonymizeDN(dn, parsed), nil
+}
+
+// dnValue returns the anonymized DN of an attribute value and false if the
+// value is no DN.
+func (a *Anonymizer) dnValue(v string) (string, bool) {
+	parsed, err := ldap.ParseDN(v)
+	if err != nil {
+		return "", false
+	}
+	return a.anonymizeDN(v, parsed), true
+}
+
+func (a *Anonymizer) anonymizeDN(dn string, parsed *ldap.DN) string {
+	changed := false
+	for _, rdn := range parsed.RDNs {
+		for _, ava := range rdn.Attributes {
+			rule, ok := a.rule(ava.Type)
+			if !ok {
+				continue
+			}
+			switch rule.Strategy {
+			case AnonymizeDrop, AnonymizeFixed:
+				rule.Strategy = AnonymizeHash
+			}
+			ava.Value = a.value(ava.Type, rule, ava.Value)
+			changed = true
+		}
+	}
+	if !changed {
+		return dn
+	}
+	return dnString(parsed)
+}
+
+// sum returns the HMAC of the normalized value.
+func (a *Anonymizer) sum(v string) []byte {
+	mac := hmac.New(sha256.New, a.Key)
+	mac.Write([]byte(normalizeValue(v)))
+	return mac.Sum(nil)
+}
+
+func (a *Anonymizer) hash(v string) string {
+	return hex.EncodeToString(a.sum(v)[:8])
+}
+
+var (
+	fakeGivenNames = []string{
+		"Alex", "Bea", "Chris", "Dana", "Eli", "Fran", "Gabe", "Hana", "Ian", "Jo",
+		"Kai", "Lee", "Mia", "Noah", "Ola", "Pat", "Quinn", "Rae", "Sam", "Tia",
+		"Uma", "Val", "Wes", "Xia", "Yves", "Zoe",
+	}
+	fakeSurnames = []string{
+		"Abbott", "Baker", "Carter", "Dalton", "Ellis", "Fisher", "Garner", "Hayes",
+		"Irwin", "Jensen", "Keller", "Lowe", "Mercer", "Nolan", "Osborne", "Porter",
+		"Quill", "Reyes", "Sutton", "Turner", "Upton", "Vance", "Walsh", "Xu",
+		"Young", "Zimmer",
+	}
+)
+
+func (a *Anonymizer) fakeName(attr string, v string) string {
+	sum := a.sum(v)
+	given := fakeGivenNames[binary.BigEndian.Uint32(sum[0:4])%uint32(len(fakeGivenNames))]
+	surname := fakeSurnames[binary.BigEndian.Uint32(sum[4:8])%uint32(len(fakeSurnames))]
+	// a suffix keeps different values apart
+	suffix := hex.EncodeToString(sum[8:10])
+	name, _, _ := strings.Cut(attr, ";")
+	switch strings.ToLower(name) {
+	case "givenname":
+		return given + " " + suffix
+	case "sn", "surname":
+		return surname + " " + suffix
+	}
+	return given + " " + surname + " " + suffix
+}
+
+func (a *Anonymizer) mask(v string) string {
+	local, domain, isMail := strings.Cut(v, "@")
+	if !isMail {
+		local = v
+	}
+	// a stream of bytes derived from the value
+	stream := a.sum(v)
+	next := func(i int) byte {
+		for i >= len(stream) {
+			stream = append(stream, a.sum(string(stream))...)
+		}
+		return stream[i]
+	}
+	var b strings.Builder
+	for i, r := range local {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteByte('0' + next(i)%10)
+		case unicode.IsUpper(r):
+			b.WriteByte('A' + next(i)%26)
+		case unicode.IsLetter(r):
+			b.WriteByte('a' + next(i)%26)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if isMail {
+		b.WriteString("@" + domain)
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package ldif_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var anonymizeLDIF = `dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: jdoe
+cn: John Doe
+sn: Doe
+mail: john.doe@example.org
+telephoneNumber: +49 (30) 1234-567
+userPassword: secret
+description: likes cats
+manager: uid=Boss,ou=People,dc=example,dc=org
+
+dn: uid=boss,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: boss
+cn: Big Boss
+sn: Boss
+`
+
+var anonymizeChanges = `dn: cn=admins,ou=Groups,dc=example,dc=org
+changetype: modify
+add: member
+member: UID=JDoe,ou=People,dc=example,dc=org
+-
+delete: userPassword
+-
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modrdn
+newrdn: uid=john
+deleteoldrdn: 1
+`
+
+func newAnonymizer() *ldif.Anonymizer {
+	return &ldif.Anonymizer{
+		Key: []byte("secret key"),
+		Rules: map[string]ldif.AnonymizeRule{
+			"uid":             {Strategy: ldif.AnonymizeHash},
+			"cn":              {Strategy: ldif.AnonymizeFakeName},
+			"SN":              {Strategy: ldif.AnonymizeFakeName},
+			"mail":            {Strategy: ldif.AnonymizeMask},
+			"telephoneNumber": {Strategy: ldif.AnonymizeMask},
+			"userPassword":    {Strategy: ldif.AnonymizeDrop},
+			"description":     {Strategy: ldif.AnonymizeFixed, Value: "-"},
+		},
+	}
+}
+
+func anonymize(t *testing.T, a *ldif.Anonymizer, in string) *ldif.LDIF {
+	t.Helper()
+	l := &ldif.LDIF{}
+	for entry, err := range ldif.TransformEntries(ldif.UnmarshalEntries(strings.NewReader(in), &ldif.LDIF{}), a.Anonymize) {
+		if err != nil {
+			t.Fatalf("anonymize: %s", err)
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+	return l
+}
+
+func TestAnonymize(t *testing.T) {
+	a := newAnonymizer()
+	l := anonymize(t, a, anonymizeLDIF)
+	jdoe, boss := l.Entries[0].Entry, l.Entries[1].Entry
+
+	uid := jdoe.GetAttributeValue("uid")
+	if !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(uid) {
+		t.Errorf("uid is not hashed: %q", uid)
+	}
+	if jdoe.DN != "uid="+uid+",ou=People,dc=example,dc=org" {
+		t.Errorf("DN does not use the hashed uid: %q", jdoe.DN)
+	}
+	if m := jdoe.GetAttributeValue("manager"); !strings.EqualFold(m, boss.DN) {
+		t.Errorf("manager %q does not reference %q", m, boss.DN)
+	}
+	if cn := jdoe.GetAttributeValue("cn"); cn == "John Doe" || len(strings.Fields(cn)) != 3 {
+		t.Errorf("cn is not a fake name: %q", cn)
+	}
+	if sn := jdoe.GetAttributeValue("sn"); sn == "Doe" || len(strings.Fields(sn)) != 2 {
+		t.Errorf("sn is not a fake surname: %q", sn)
+	}
+	mail := jdoe.GetAttributeValue("mail")
+	if mail == "john.doe@example.org" || !regexp.MustCompile(`^[a-z]{4}\.[a-z]{3}@example\.org$`).MatchString(mail) {
+		t.Errorf("mail is not masked: %q", mail)
+	}
+	phone := jdoe.GetAttributeValue("telephoneNumber")
+	if phone == "+49 (30) 1234-567" || !regexp.MustCompile(`^\+\d\d \(\d\d\) \d{4}-\d{3}$`).MatchString(phone) {
+		t.Errorf("telephoneNumber is not masked: %q", phone)
+	}
+	if v := jdoe.GetAttributeValues("userPassword"); len(v) != 0 {
+		t.Errorf("userPassword is not dropped: %q", v)
+	}
+	if v := jdoe.GetAttributeValue("description"); v != "-" {
+		t.Errorf("description is not fixed: %q", v)
+	}
+	if v := jdoe.GetAttributeValues("objectClass"); len(v) != 1 || v[0] != "inetOrgPerson" {
+		t.Errorf("objectClass is changed: %q", v)
+	}
+
+	again := anonymize(t, newAnonymizer(), anonymizeLDIF)
+	if again.Entries[0].Entry.DN != jdoe.DN || again.Entries[0].Entry.GetAttributeValue("mail") != mail {
+		t.Error("anonymizing is not deterministic")
+	}
+	other := newAnonymizer()
+	other.Key = []byte("other key")
+	if anonymize(t, other, anonymizeLDIF).Entries[0].Entry.DN == jdoe.DN {
+		t.Error("the hash does not depend on the key")
+	}
+}
+
+func TestAnonymizeChanges(t *testing.T) {
+	content := anonymize(t, newAnonymizer(), anonymizeLDIF)
+	l := anonymize(t, newAnonymizer(), anonymizeChanges)
+
+	mod := l.Entries[0].Modify
+	if len(mod.Changes) != 1 {
+		t.Fatalf("userPassword change is not dropped: %+v", mod.Changes)
+	}
+	if member := mod.Changes[0].Modification.Vals[0]; !strings.EqualFold(member, content.Entries[0].Entry.DN) {
+		t.Errorf("member %q does not reference %q", member, content.Entries[0].Entry.DN)
+	}
+	moddn := l.Entries[1].ModifyDN
+	if moddn.DN != content.Entries[0].Entry.DN {
+		t.Errorf("modrdn DN %q is not %q", moddn.DN, content.Entries[0].Entry.DN)
+	}
+	if moddn.NewRDN == "uid=john" || !strings.HasPrefix(moddn.NewRDN, "uid=") {
+		t.Errorf("newrdn is not anonymized: %q", moddn.NewRDN)
+	}
+	if _, err := ldif.Marshal(l); err != nil {
+		t.Errorf("marshal: %s", err)
+	}
+
+	for _, err := range ldif.TransformEntries(ldif.UnmarshalEntries(strings.NewReader(anonymizeChanges), &ldif.LDIF{}), (&ldif.Anonymizer{}).Anonymize) {
+		if err == nil {
+			t.Error("no key: expected an error")
+		}
+		break
+	}
+}
@@ -15,8 +15,16 @@ import (
 // By default, it returns on the first error. To continue with applying the
 // LDIF, set the continueOnErr argument to true - in this case the errors
 // are logged with log.Printf()
+//
+// The operational attributes of the entries are handled as given by
+// l.Operational, see HandleOperational. Records dropped by it are not sent.
 func (l *LDIF) Apply(conn ldap.Client, continueOnErr bool) error {
+	handle := HandleOperational(l.Operational)
 	for _, entry := range l.Entries {
+		if entry, _ = handle(entry); entry == nil {
+			// a modify of operational attributes only
+			continue
+		}
 		switch {
 		case entry.Entry != nil:
 			add := ldap.NewAddRequest(entry.Entry.DN, nil)
@@ -51,6 +59,15 @@ func (l *LDIF) Apply(conn ldap.Client, continueOnErr bool) error {
 				}
 				return fmt.Errorf("failed to modify %s: %s", entry.Modify.DN, err)
 			}
+
+		case entry.ModifyDN != nil:
+			if err := conn.ModifyDN(entry.ModifyDN); err != nil {
+				if continueOnErr {
+					log.Printf("ERROR: Failed to modify dn %s: %s", entry.ModifyDN.DN, err)
+					continue
+				}
+				return fmt.Errorf("failed to modify dn %s: %s", entry.ModifyDN.DN, err)
+			}
 		}
 	}
 	return nil
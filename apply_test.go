@@ -15,6 +15,7 @@ type recordingConn struct {
 	adds    []*ldap.AddRequest
 	dels    []*ldap.DelRequest
 	mods    []*ldap.ModifyRequest
+	mdns    []*ldap.ModifyDNRequest
 	failAdd bool
 }
 
@@ -36,6 +37,11 @@ func (c *recordingConn) Modify(r *ldap.ModifyRequest) error {
 	return nil
 }
 
+func (c *recordingConn) ModifyDN(r *ldap.ModifyDNRequest) error {
+	c.mdns = append(c.mdns, r)
+	return nil
+}
+
 // A content entry must be applied as an Add without panicking.
 func TestApplyContentEntry(t *testing.T) {
 	l, err := ldif.Parse("dn: uid=someone,dc=example,dc=org\ncn: Someone\n")
@@ -79,3 +85,18 @@ func TestApplyContinueOnErr(t *testing.T) {
 		t.Errorf("expected to stop after first failure, got %d adds", len(conn.adds))
 	}
 }
+
+// A modrdn record must be applied as a ModifyDN request.
+func TestApplyModifyDN(t *testing.T) {
+	l, err := ldif.Parse("dn: uid=a,dc=example,dc=org\nchangetype: modrdn\nnewrdn: uid=b\ndeleteoldrdn: 1\n")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	conn := &recordingConn{}
+	if err := l.Apply(conn, false); err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+	if len(conn.mdns) != 1 || conn.mdns[0].NewRDN != "uid=b" {
+		t.Errorf("expected 1 moddn request, got %#v", conn.mdns)
+	}
+}
@@ -0,0 +1,180 @@
+package ldif
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// caseIgnoreAttributes are the attributes whose values Canonicalize
+// compares case-insensitively by default.
+var caseIgnoreAttributes = []string{
+	"objectClass", "cn", "sn", "givenName", "ou", "o", "dc", "uid", "mail", "l", "st", "c",
+}
+
+// CanonicalOptions configure Canonicalize.
+type CanonicalOptions struct {
+	// CaseIgnore are the attributes whose values are compared
+	// case-insensitively when removing duplicate values, the values of all
+	// other attributes are compared exactly. By default these are
+	// objectClass, cn, sn, givenName, ou, o, dc, uid, mail, l, st and c.
+	CaseIgnore []string
+	// Names maps attribute names and aliases (compared case-insensitively)
+	// to their canonical names, e.g. "surname" and "SN" to "sn", see
+	// SchemaNames. Attributes which are not in the map get the name of
+	// their first occurrence in the entry.
+	Names map[string]string
+}
+
+// Canonicalize returns the entries in a canonical form, so two exports of
+// the same data give the same output:
+//
+//   - entries with the same (normalized) DN are merged into one, which has
+//     the DN of the first
+//   - attributes with the same name (after mapping it with Names, compared
+//     case-insensitively) are merged into one
+//   - duplicate values of an attribute are removed, the first one is kept
+//   - the entries are sorted by their DN, parents before their children,
+//     the attributes by name with objectClass first, and the values
+//
+// The entries are changed in place. An error wrapping ErrInvalidDN is
+// returned for an entry with an invalid DN.
+func Canonicalize(entries []*ldap.Entry, opts *CanonicalOptions) ([]*ldap.Entry, error) {
+	if opts == nil {
+		opts = &CanonicalOptions{}
+	}
+	caseIgnore := opts.CaseIgnore
+	if caseIgnore == nil {
+		caseIgnore = caseIgnoreAttributes
+	}
+	ignore := make(map[string]bool, len(caseIgnore))
+	for _, name := range caseIgnore {
+		ignore[strings.ToLower(name)] = true
+	}
+	names := make(map[string]string, len(opts.Names))
+	for alias, name := range opts.Names {
+		names[strings.ToLower(alias)] = name
+	}
+
+	type canonical struct {
+		entry *ldap.Entry
+		keys  []string // normalized RDNs, the root first
+	}
+	var result []*canonical
+	byDN := make(map[string]*canonical)
+	for _, entry := range entries {
+		dn, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN, err)
+		}
+		key := normalizeParsedDN(dn)
+		if c, ok := byDN[key]; ok {
+			c.entry.Attributes = append(c.entry.Attributes, entry.Attributes...)
+			continue
+		}
+		keys := rdnKeys(dn)
+		slices.Reverse(keys)
+		c := &canonical{entry: entry, keys: keys}
+		byDN[key] = c
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b *canonical) int { return slices.Compare(a.keys, b.keys) })
+
+	canonicalized := make([]*ldap.Entry, len(result))
+	for i, c := range result {
+		canonicalizeAttributes(c.entry, names, ignore)
+		canonicalized[i] = c.entry
+	}
+	return canonicalized, nil
+}
+
+// canonicalizeAttributes renames, merges and sorts the attributes of the
+// entry and removes their duplicate values.
+func canonicalizeAttributes(entry *ldap.Entry, names map[string]string, ignore map[string]bool) {
+	var attrs []*ldap.EntryAttribute
+	byName := make(map[string]*ldap.EntryAttribute)
+	for _, a := range entry.Attributes {
+		name := canonicalName(a.Name, names)
+		key := strings.ToLower(name)
+		if existing, ok := byName[key]; ok {
+			setValues(existing, append(slices.Clip(existing.Values), a.Values...))
+			continue
+		}
+		a.Name = name
+		byName[key] = a
+		attrs = append(attrs, a)
+	}
+	for _, a := range attrs {
+		base, _, _ := strings.Cut(a.Name, ";")
+		fold := ignore[strings.ToLower(base)]
+		seen := make(map[string]bool, len(a.Values))
+		var values []string
+		for _, v := range a.Values {
+			key := v
+			if fold {
+				key = strings.ToLower(v)
+			}
+			if !seen[key] {
+				seen[key] = true
+				values = append(values, v)
+			}
+		}
+		slices.Sort(values)
+		setValues(a, values)
+	}
+	slices.SortStableFunc(attrs, func(a, b *ldap.EntryAttribute) int {
+		ao, bo := strings.EqualFold(a.Name, "objectClass"), strings.EqualFold(b.Name, "objectClass")
+		switch {
+		case ao && !bo:
+			return -1
+		case bo && !ao:
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	entry.Attributes = attrs
+}
+
+// canonicalName maps the attribute name without its options, the options
+// are lower cased.
+func canonicalName(attr string, names map[string]string) string {
+	name, options, hasOptions := strings.Cut(attr, ";")
+	if mapped, ok := names[strings.ToLower(name)]; ok {
+		name = mapped
+	}
+	if hasOptions {
+		return name + ";" + strings.ToLower(options)
+	}
+	return name
+}
+
+var schemaName = regexp.MustCompile(`(?i)\bNAME\s+(?:'([^']*)'|\(([^)]*)\))`)
+
+// SchemaNames returns the canonical attribute names of the attribute type
+// descriptions of RFC 4512 (the attributeTypes values of a subschema
+// entry) for CanonicalOptions.Names: each name of an attribute type is
+// mapped to its first name, e.g. "commonName" to "cn". Descriptions
+// without a name are skipped.
+func SchemaNames(attributeTypes []string) map[string]string {
+	names := make(map[string]string)
+	for _, desc := range attributeTypes {
+		m := schemaName.FindStringSubmatch(desc)
+		if m == nil {
+			continue
+		}
+		list := []string{m[1]}
+		if m[1] == "" {
+			list = strings.Fields(strings.ReplaceAll(m[2], "'", " "))
+		}
+		if len(list) == 0 {
+			continue
+		}
+		for _, alias := range list {
+			names[strings.ToLower(alias)] = list[0]
+		}
+	}
+	return names
+}
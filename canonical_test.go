@@ -0,0 +1,103 @@
+package ldif_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var canonicalEntries = `dn: uid=jdoe,ou=People,dc=example,dc=org
+uid: jdoe
+surname: Doe
+objectClass: person
+objectClass: inetOrgPerson
+mail: JDoe@example.org
+mail: jdoe@example.org
+description: Foo
+description: foo
+
+dn: ou=People,dc=example,dc=org
+ou: People
+objectClass: organizationalUnit
+
+dn: UID=JDoe,ou=people,dc=example,dc=org
+SN: Doe
+objectclass: Person
+cn;Lang-EN: John Doe
+`
+
+var canonicalExpected = `version: 1
+dn: ou=People,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+objectClass: person
+cn;lang-en: John Doe
+description: Foo
+description: foo
+mail: JDoe@example.org
+sn: Doe
+uid: jdoe
+
+`
+
+func TestCanonicalize(t *testing.T) {
+	l := &ldif.LDIF{}
+	if err := ldif.Unmarshal(strings.NewReader(canonicalEntries), l); err != nil {
+		t.Fatal(err)
+	}
+	var entries []*ldap.Entry
+	for _, e := range l.Entries {
+		entries = append(entries, e.Entry)
+	}
+	names := ldif.SchemaNames([]string{
+		"( 2.5.4.4 NAME ( 'sn' 'surname' ) SUP name )",
+		"( 2.5.4.3 NAME ( 'cn' 'commonName' ) SUP name )",
+		"( 2.5.4.0 NAME 'objectClass' EQUALITY objectIdentifierMatch )",
+	})
+	if names["surname"] != "sn" || names["commonname"] != "cn" || names["objectclass"] != "objectClass" {
+		t.Errorf("unexpected schema names: %v", names)
+	}
+	entries, err := ldif.Canonicalize(entries, &ldif.CanonicalOptions{Names: names})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &ldif.LDIF{Version: 1}
+	for _, e := range entries {
+		out.Entries = append(out.Entries, &ldif.Entry{Entry: e})
+	}
+	s, err := ldif.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != canonicalExpected {
+		t.Errorf("expected:\n%s\ngot:\n%s", canonicalExpected, s)
+	}
+}
+
+func TestCanonicalizeCaseIgnore(t *testing.T) {
+	entry := ldap.NewEntry("cn=foo,dc=example,dc=org", map[string][]string{
+		"description": {"b", "B", "a"},
+		"mail":        {"x@example.org", "X@example.org"},
+	})
+	entries, err := ldif.Canonicalize([]*ldap.Entry{entry}, &ldif.CanonicalOptions{CaseIgnore: []string{"Description"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := entries[0].GetAttributeValues("description"); strings.Join(v, ",") != "a,b" {
+		t.Errorf("description: got %v", v)
+	}
+	if v := entries[0].GetAttributeValues("mail"); len(v) != 2 {
+		t.Errorf("mail: got %v", v)
+	}
+
+	_, err = ldif.Canonicalize([]*ldap.Entry{ldap.NewEntry("invalid", nil)}, nil)
+	if !errors.Is(err, ldif.ErrInvalidDN) {
+		t.Errorf("invalid DN: got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package ldif
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// firstValue returns the first value of the attribute, compared
+// case-insensitively, or an empty string.
+func firstValue(entry *ldap.Entry, name string) string {
+	if values := attributeValues(entry, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// FromAccessLog converts an entry of the OpenLDAP accesslog overlay
+// (slapo-accesslog, usually below cn=accesslog) to the change record of
+// the logged write operation, it is a Transform. The operation is given by
+// reqType (add, delete, modify or modrdn), reqDN, the modifications in
+// reqMod ("mail:+ jdoe@example.org" adds a value, ":-" deletes, ":="
+// replaces, ":#" increments, an attribute without a value like "mail:-"
+// deletes or replaces all values) and reqNewRDN, reqDeleteOldRDN and
+// reqNewSuperior.
+//
+// Entries of other operations (bind, search, ...), of failed operations
+// (reqResult other than 0) and entries without reqType, like cn=accesslog
+// itself, are dropped, i.e. nil is returned. Consecutive values of reqMod
+// for the same attribute and operation become one change, each value of an
+// increment its own ldap.IncrementAttribute change.
+//
+// The operational attributes (see IsOperational) in reqMod, like
+// modifiersName or entryCSN, are left out, as the server sets them when the
+// change is replayed. A modify of operational attributes only is dropped.
+//
+// The accesslog entries are not sorted by time in an export, the caller
+// must sort them by reqStart to replay the changes in order.
+func FromAccessLog(record *Entry) (*Entry, error) {
+	entry := contentEntry(record)
+	if entry == nil {
+		return nil, fmt.Errorf("%s is a change record, the accesslog conversion needs content records", record.DN())
+	}
+	reqType := strings.ToLower(firstValue(entry, "reqType"))
+	if result := firstValue(entry, "reqResult"); result != "" && result != "0" {
+		return nil, nil
+	}
+	dn := firstValue(entry, "reqDN")
+	switch reqType {
+	case "add", "delete", "modify", "modrdn":
+		if dn == "" {
+			return nil, fmt.Errorf("%s: reqDN is missing", entry.DN)
+		}
+	default:
+		return nil, nil
+	}
+
+	switch reqType {
+	case "delete":
+		return &Entry{Del: ldap.NewDelRequest(dn, nil)}, nil
+	case "modrdn":
+		newRDN := firstValue(entry, "reqNewRDN")
+		if newRDN == "" {
+			return nil, fmt.Errorf("%s: reqNewRDN is missing", entry.DN)
+		}
+		deleteOldRDN := strings.EqualFold(firstValue(entry, "reqDeleteOldRDN"), "TRUE")
+		return &Entry{ModifyDN: ldap.NewModifyDNRequest(dn, newRDN, deleteOldRDN, firstValue(entry, "reqNewSuperior"))}, nil
+	}
+
+	mods, err := accessLogMods(attributeValues(entry, "reqMod"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", entry.DN, err)
+	}
+	mods = slices.DeleteFunc(mods, func(m *accessLogMod) bool { return IsOperational(m.attr) })
+	if reqType == "add" {
+		add := ldap.NewAddRequest(dn, nil)
+		for _, m := range mods {
+			if m.op != '+' {
+				return nil, fmt.Errorf("%s: invalid reqMod %s:%c in add", entry.DN, m.attr, m.op)
+			}
+			add.Attribute(m.attr, m.values)
+		}
+		return &Entry{Add: add}, nil
+	}
+	if len(mods) == 0 {
+		return nil, nil
+	}
+	modify := ldap.NewModifyRequest(dn, nil)
+	for _, m := range mods {
+		switch m.op {
+		case '+':
+			modify.Add(m.attr, m.values)
+		case '-':
+			modify.Delete(m.attr, m.values)
+		case '=':
+			modify.Replace(m.attr, m.values)
+		case '#':
+			for _, v := range m.values {
+				modify.Increment(m.attr, v)
+			}
+		}
+	}
+	return &Entry{Modify: modify}, nil
+}
+
+// accessLogMod is a modification of reqMod.
+type accessLogMod struct {
+	attr   string
+	op     byte
+	values []string
+}
+
+// accessLogMods parses the reqMod values.
+func accessLogMods(reqMods []string) ([]*accessLogMod, error) {
+	var mods []*accessLogMod
+	for _, v := range reqMods {
+		attr, rest, ok := strings.Cut(v, ":")
+		if !ok || attr == "" || rest == "" {
+			return nil, fmt.Errorf("invalid reqMod %q", v)
+		}
+		op := rest[0]
+		switch op {
+		case '+', '-', '=', '#':
+		default:
+			return nil, fmt.Errorf("invalid operation %q in reqMod %q", op, v)
+		}
+		// "attr:op value" or "attr:op" without a value
+		rest = rest[1:]
+		hasValue := rest != ""
+		if hasValue {
+			if rest[0] != ' ' {
+				return nil, fmt.Errorf("invalid reqMod %q", v)
+			}
+			rest = rest[1:]
+		}
+		if n := len(mods); n > 0 && mods[n-1].attr == attr && mods[n-1].op == op && hasValue && len(mods[n-1].values) > 0 {
+			mods[n-1].values = append(mods[n-1].values, rest)
+			continue
+		}
+		m := &accessLogMod{attr: attr, op: op}
+		if hasValue {
+			m.values = []string{rest}
+		}
+		mods = append(mods, m)
+	}
+	return mods, nil
+}
+
+// FromRetroChangelog converts an entry of the retro changelog of 389-ds
+// (usually below cn=changelog) to its change record, it is a Transform.
+// The change is given by changeType (add, delete, modify or modrdn),
+// targetDn, the attributes of an add or the modifications of a modify as
+// LDIF in changes, and newRDN, deleteOldRDN and newSuperior.
+//
+// Entries without changeType, like cn=changelog itself, are dropped, i.e.
+// nil is returned. As with FromAccessLog, the operational attributes are
+// left out of the changes and a modify of operational attributes only is
+// dropped. The caller must sort the entries by changeNumber to replay the
+// changes in order.
+func FromRetroChangelog(record *Entry) (*Entry, error) {
+	entry := contentEntry(record)
+	if entry == nil {
+		return nil, fmt.Errorf("%s is a change record, the changelog conversion needs content records", record.DN())
+	}
+	changeType := strings.ToLower(firstValue(entry, "changeType"))
+	if changeType == "" {
+		return nil, nil
+	}
+	dn := firstValue(entry, "targetDn")
+	if dn == "" {
+		return nil, fmt.Errorf("%s: targetDn is missing", entry.DN)
+	}
+
+	switch changeType {
+	case "delete":
+		return &Entry{Del: ldap.NewDelRequest(dn, nil)}, nil
+	case "modrdn":
+		newRDN := firstValue(entry, "newRDN")
+		if newRDN == "" {
+			return nil, fmt.Errorf("%s: newRDN is missing", entry.DN)
+		}
+		deleteOldRDN := strings.EqualFold(firstValue(entry, "deleteOldRDN"), "TRUE")
+		return &Entry{ModifyDN: ldap.NewModifyDNRequest(dn, newRDN, deleteOldRDN, firstValue(entry, "newSuperior"))}, nil
+	case "add", "modify":
+	default:
+		return nil, fmt.Errorf("%s: invalid changeType %s", entry.DN, changeType)
+	}
+
+	// the changes are the body of an LDIF change record, 389-ds ends them
+	// with a NUL byte
+	changes := strings.TrimRight(firstValue(entry, "changes"), "\x00\n ")
+	if changes == "" {
+		return nil, fmt.Errorf("%s: changes are missing", entry.DN)
+	}
+	record = nil
+	l := &LDIF{Mode: ModeLenient}
+	for e, err := range UnmarshalEntries(strings.NewReader(dnLine(dn)+"\nchangetype: "+changeType+"\n"+changes+"\n"), l) {
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid changes: %w", entry.DN, err)
+		}
+		if record != nil {
+			return nil, fmt.Errorf("%s: invalid changes: more than one record", entry.DN)
+		}
+		record = e
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%s: invalid changes: no record", entry.DN)
+	}
+	return stripOperational(record), nil
+}
@@ -0,0 +1,253 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var accessLogLDIF = `dn: cn=accesslog
+objectClass: auditContainer
+cn: accesslog
+
+dn: reqStart=20240101000000.000001Z,cn=accesslog
+objectClass: auditAdd
+reqStart: 20240101000000.000001Z
+reqType: add
+reqDN: uid=jdoe,ou=People,dc=example,dc=org
+reqResult: 0
+reqMod: objectClass:+ inetOrgPerson
+reqMod: uid:+ jdoe
+reqMod: mail:+ jdoe@example.org
+reqMod: mail:+ john@example.org
+reqMod: entryUUID:+ 0b6e5c3e-8f6a-103e-8e5b-8d1f3a1e6c11
+reqMod: creatorsName:+ cn=admin,dc=example,dc=org
+
+dn: reqStart=20240101000000.000002Z,cn=accesslog
+objectClass: auditModify
+reqStart: 20240101000000.000002Z
+reqType: modify
+reqDN: uid=jdoe,ou=People,dc=example,dc=org
+reqResult: 0
+reqMod: mail:- john@example.org
+reqMod: description:=
+reqMod: telephoneNumber:= 123
+reqMod: telephoneNumber:= 456
+reqMod: title:-
+reqMod: mail:+ j.doe@example.org
+reqMod: loginCount:# 1
+reqMod: entryCSN:= 20240101000000.000002Z#000000#000#000000
+reqMod: modifiersName:= cn=admin,dc=example,dc=org
+reqMod: modifyTimestamp:= 20240101000000Z
+
+dn: reqStart=20240101000000.000003Z,cn=accesslog
+objectClass: auditModify
+reqType: modify
+reqDN: uid=jdoe,ou=People,dc=example,dc=org
+reqResult: 0
+reqMod: pwdFailureTime:+ 20240101000000Z
+reqMod: modifyTimestamp:= 20240101000000Z
+
+dn: reqStart=20240101000000.000003Z,cn=accesslog
+objectClass: auditModRDN
+reqType: modrdn
+reqDN: uid=jdoe,ou=People,dc=example,dc=org
+reqResult: 0
+reqNewRDN: uid=john
+reqDeleteOldRDN: TRUE
+reqNewSuperior: ou=Staff,dc=example,dc=org
+
+dn: reqStart=20240101000000.000004Z,cn=accesslog
+objectClass: auditModify
+reqType: modify
+reqDN: uid=gone,ou=People,dc=example,dc=org
+reqResult: 32
+reqMod: mail:+ gone@example.org
+
+dn: reqStart=20240101000000.000005Z,cn=accesslog
+objectClass: auditBind
+reqType: bind
+reqDN: uid=john,ou=Staff,dc=example,dc=org
+reqResult: 0
+
+dn: reqStart=20240101000000.000006Z,cn=accesslog
+objectClass: auditDelete
+reqType: delete
+reqDN: uid=john,ou=Staff,dc=example,dc=org
+reqResult: 0
+`
+
+var accessLogExpected = `dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: add
+objectClass: inetOrgPerson
+uid: jdoe
+mail: jdoe@example.org
+mail: john@example.org
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modify
+delete: mail
+mail: john@example.org
+-
+replace: description
+-
+replace: telephoneNumber
+telephoneNumber: 123
+telephoneNumber: 456
+-
+delete: title
+-
+add: mail
+mail: j.doe@example.org
+-
+increment: loginCount
+loginCount: 1
+-
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modrdn
+newrdn: uid=john
+deleteoldrdn: 1
+newsuperior: ou=Staff,dc=example,dc=org
+
+dn: uid=john,ou=Staff,dc=example,dc=org
+changetype: delete
+
+`
+
+func TestFromAccessLog(t *testing.T) {
+	l := &ldif.LDIF{}
+	for e, err := range ldif.TransformEntries(ldif.UnmarshalEntries(strings.NewReader(accessLogLDIF), &ldif.LDIF{}), ldif.FromAccessLog) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Entries = append(l.Entries, e)
+	}
+	s, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != accessLogExpected {
+		t.Errorf("expected:\n%s\ngot:\n%s", accessLogExpected, s)
+	}
+
+	// the converted records can be read back
+	l2, err := ldif.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := canonical(l2), canonical(l); got != want {
+		t.Errorf("round-trip: expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFromAccessLogErrors(t *testing.T) {
+	for _, reqMod := range []string{"mail", "mail:", "mail:* foo", "mail:+foo", ":+ foo"} {
+		entry := ldap.NewEntry("reqStart=1,cn=accesslog", map[string][]string{
+			"reqType": {"modify"},
+			"reqDN":   {"uid=jdoe,dc=example,dc=org"},
+			"reqMod":  {reqMod},
+		})
+		if _, err := ldif.FromAccessLog(&ldif.Entry{Entry: entry}); err == nil {
+			t.Errorf("reqMod %q: no error", reqMod)
+		}
+	}
+	entry := ldap.NewEntry("reqStart=1,cn=accesslog", map[string][]string{"reqType": {"delete"}})
+	if _, err := ldif.FromAccessLog(&ldif.Entry{Entry: entry}); err == nil {
+		t.Error("missing reqDN: no error")
+	}
+	if _, err := ldif.FromAccessLog(&ldif.Entry{Del: ldap.NewDelRequest("reqStart=1,cn=accesslog", nil)}); err == nil {
+		t.Error("change record: no error")
+	}
+}
+
+func changelogEntry(number string, attrs map[string][]string) *ldif.Entry {
+	attrs["changeNumber"] = []string{number}
+	return &ldif.Entry{Entry: ldap.NewEntry("changenumber="+number+",cn=changelog", attrs)}
+}
+
+func TestFromRetroChangelog(t *testing.T) {
+	add, err := ldif.FromRetroChangelog(changelogEntry("1", map[string][]string{
+		"changeType": {"add"},
+		"targetDn":   {"uid=jdoe,ou=People,dc=example,dc=org"},
+		"changes":    {"objectClass: top\nobjectClass: inetOrgPerson\nuid: jdoe\nsn: Doe\n\x00"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if add.Add == nil || add.Add.DN != "uid=jdoe,ou=People,dc=example,dc=org" || len(add.Add.Attributes) != 3 {
+		t.Fatalf("add: got %+v", add)
+	}
+
+	l := &ldif.LDIF{}
+	for _, e := range []*ldif.Entry{
+		changelogEntry("2", map[string][]string{
+			"changeType": {"modify"},
+			"targetDn":   {"uid=jdoe,ou=People,dc=example,dc=org"},
+			"changes":    {"replace: sn\nsn: Smith\n-\nadd: mail\nmail: jdoe@example.org\n-\nreplace: modifiersName\nmodifiersName: cn=Directory Manager\n-\n\x00"},
+		}),
+		changelogEntry("3", map[string][]string{
+			"changeType": {"modify"},
+			"targetDn":   {"uid=jdoe,ou=People,dc=example,dc=org"},
+			"changes":    {"replace: modifiersName\nmodifiersName: cn=Directory Manager\n-\nreplace: modifyTimestamp\nmodifyTimestamp: 20240101000000Z\n-\n\x00"},
+		}),
+		changelogEntry("3", map[string][]string{
+			"changeType":   {"modrdn"},
+			"targetDn":     {"uid=jdoe,ou=People,dc=example,dc=org"},
+			"newRDN":       {"uid=john"},
+			"deleteOldRDN": {"FALSE"},
+		}),
+		changelogEntry("4", map[string][]string{
+			"changeType": {"delete"},
+			"targetDn":   {"uid=john,ou=People,dc=example,dc=org"},
+		}),
+		{Entry: ldap.NewEntry("cn=changelog", map[string][]string{"cn": {"changelog"}})},
+	} {
+		converted, err := ldif.FromRetroChangelog(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if converted != nil {
+			l.Entries = append(l.Entries, converted)
+		}
+	}
+	expected := `dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modify
+replace: sn
+sn: Smith
+-
+add: mail
+mail: jdoe@example.org
+-
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modrdn
+newrdn: uid=john
+deleteoldrdn: 0
+
+dn: uid=john,ou=People,dc=example,dc=org
+changetype: delete
+
+`
+	s, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+
+	for name, attrs := range map[string]map[string][]string{
+		"missing targetDn": {"changeType": {"delete"}},
+		"invalid type":     {"changeType": {"rename"}, "targetDn": {"cn=foo"}},
+		"invalid changes":  {"changeType": {"modify"}, "targetDn": {"cn=foo"}, "changes": {"bogus: sn\n-\n"}},
+		"missing changes":  {"changeType": {"add"}, "targetDn": {"cn=foo"}},
+		"missing newRDN":   {"changeType": {"modrdn"}, "targetDn": {"cn=foo"}},
+	} {
+		if _, err := ldif.FromRetroChangelog(changelogEntry("5", attrs)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -129,3 +129,60 @@ func TestLDIFParseRFC2849Example6(t *testing.T) {
 		t.Errorf("RFC 2849 example 6: no replacing of telephonenumber")
 	}
 }
+
+var ldifModRDN = `version: 1
+dn: cn=Paul Jensen, ou=Product Development, dc=airius, dc=com
+changetype: modrdn
+newrdn: cn=Paula Jensen
+deleteoldrdn: 1
+
+dn: ou=PD Accountants, ou=Product Development, dc=airius, dc=com
+changetype: moddn
+newrdn: ou=Product Development Accountants
+deleteoldrdn: 0
+newsuperior: ou=Accounting, dc=airius, dc=com
+`
+
+func TestParseModRDN(t *testing.T) {
+	l, err := ldif.Parse(ldifModRDN)
+	if err != nil {
+		t.Fatalf("Failed to parse modrdn: %s", err)
+	}
+	if len(l.Entries) != 2 {
+		t.Fatalf("invalid number of entries parsed: %d", len(l.Entries))
+	}
+	first := l.Entries[0].ModifyDN
+	if first == nil || first.NewRDN != "cn=Paula Jensen" || !first.DeleteOldRDN || first.NewSuperior != "" {
+		t.Errorf("wrong first modrdn request: %#v", first)
+	}
+	second := l.Entries[1].ModifyDN
+	if second == nil || second.DeleteOldRDN || second.NewSuperior != "ou=Accounting, dc=airius, dc=com" {
+		t.Errorf("wrong second modrdn request: %#v", second)
+	}
+
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Failed to marshal modrdn: %s", err)
+	}
+	l2, err := ldif.Parse(out)
+	if err != nil {
+		t.Fatalf("Failed to parse marshalled modrdn: %s", err)
+	}
+	if *l2.Entries[1].ModifyDN != *second {
+		t.Errorf("modrdn round trip failed: %#v", l2.Entries[1].ModifyDN)
+	}
+}
+
+func TestParseModRDNInvalid(t *testing.T) {
+	for name, in := range map[string]string{
+		"missing newrdn":  "dn: cn=a,dc=example,dc=com\nchangetype: modrdn\n",
+		"bad deleteold":   "dn: cn=a,dc=example,dc=com\nchangetype: modrdn\nnewrdn: cn=b\ndeleteoldrdn: yes\n",
+		"wrong attribute": "dn: cn=a,dc=example,dc=com\nchangetype: modrdn\nnewrdn: cn=b\ncn: b\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ldif.Parse(in); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldif"
+)
+
+var convertCommand = &command{
+	name:    "convert",
+	args:    "[file ...]",
+	summary: "convert records between LDIF, JSON and CSV",
+}
+
+func init() {
+	convertCommand.run = runConvert
+	register(convertCommand)
+}
+
+var formats = []string{"ldif", "json", "csv"}
+
+// logFormats are the input formats of change logs, which are read as LDIF
+// and converted to change records.
+var logFormats = []string{"accesslog", "changelog"}
+
+// formatFromName returns the format for the extension of the file name
+// (ignoring a compression extension), "ldif" if it is not known.
+func formatFromName(name string) string {
+	if ldif.CompressionFromExtension(name) != ldif.CompressionNone {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	}
+	return "ldif"
+}
+
+func validFormat(f string) bool {
+	for _, v := range formats {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// runConvert reads all inputs and writes their records in the output
+// format, the attributes are sorted like in fmt. CSV holds content records
+// only, for CSV input a DN template is required (-dn or in the -mapping
+// file). Without a mapping, CSV output has a column for each attribute.
+// The entries of an accesslog or changelog are sorted by reqStart or
+// changeNumber and converted to change records.
+func runConvert(e *env, args []string) int {
+	fs := e.flags(convertCommand)
+	template := parserFlags(fs)
+	from := fs.String("from", "", "input `format`: ldif, json, csv, accesslog (OpenLDAP) or changelog (389-ds retro changelog), default from the file extension")
+	to := fs.String("to", "ldif", "output `format`: ldif, json or csv")
+	output := fs.String("o", "", "output `file`, default stdout, compressed by its extension")
+	mappingFile := fs.String("mapping", "", "JSON `file` with the CSV mapping (ldif.CSVMapping)")
+	dnTemplate := fs.String("dn", "", "DN `template` for CSV input, e.g. \"uid={uid},ou=People,dc=example,dc=com\"")
+	separator := fs.String("separator", "|", "separator of multiple values in a CSV cell, if there is no mapping")
+	fold := fs.Int("fold", 0, "line `width` for folding LDIF, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *from != "" && !validFormat(*from) && !slices.Contains(logFormats, *from) {
+		e.errorf("invalid input format %q", *from)
+		return exitUsage
+	}
+	if !validFormat(*to) {
+		e.errorf("invalid output format %q", *to)
+		return exitUsage
+	}
+
+	var mapping *ldif.CSVMapping
+	if *mappingFile != "" {
+		data, err := os.ReadFile(*mappingFile)
+		if err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+		mapping = &ldif.CSVMapping{}
+		if err := json.Unmarshal(data, mapping); err != nil {
+			e.errorf("invalid mapping %s: %s", *mappingFile, err)
+			return exitError
+		}
+	} else {
+		mapping = &ldif.CSVMapping{Separator: *separator}
+	}
+	if *dnTemplate != "" {
+		mapping.DN = *dnTemplate
+	}
+
+	var entries []*ldif.Entry
+	for _, name := range inputNames(fs.Args()) {
+		format := *from
+		if format == "" {
+			format = formatFromName(name)
+		}
+		records, err := e.readRecords(name, format, template, mapping)
+		if err != nil {
+			e.errorf("%s: %s", displayName(name), err)
+			return exitError
+		}
+		entries = append(entries, records...)
+	}
+	sortAttributes(entries)
+
+	w, err := e.create(*output)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	err = writeRecords(w, *to, entries, mapping, *fold)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func (e *env) readRecords(name, format string, template *ldif.LDIF, mapping *ldif.CSVMapping) ([]*ldif.Entry, error) {
+	data, err := e.readAll(name)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return ldif.FromJSON(data)
+	case "csv":
+		if mapping.DN == "" {
+			return nil, errors.New("CSV input requires a DN template (-dn or -mapping)")
+		}
+		var entries []*ldif.Entry
+		for entry, err := range ldif.UnmarshalCSV(bytes.NewReader(data), mapping) {
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+	l := parser(template)
+	if err := ldif.Unmarshal(bytes.NewReader(data), l); err != nil {
+		return nil, err
+	}
+	switch format {
+	case "accesslog":
+		return convertLog(l.Entries, "reqStart", ldif.FromAccessLog)
+	case "changelog":
+		return convertLog(l.Entries, "changeNumber", ldif.FromRetroChangelog)
+	}
+	return l.Entries, nil
+}
+
+// convertLog sorts the log entries by the attribute, numbers are compared
+// numerically, and converts them to change records.
+func convertLog(entries []*ldif.Entry, sortBy string, convert ldif.Transform) ([]*ldif.Entry, error) {
+	key := func(e *ldif.Entry) string {
+		if e.Entry == nil {
+			return ""
+		}
+		for _, a := range e.Entry.Attributes {
+			if strings.EqualFold(a.Name, sortBy) && len(a.Values) > 0 {
+				return a.Values[0]
+			}
+		}
+		return ""
+	}
+	// numbers sort by their length first
+	slices.SortStableFunc(entries, func(a, b *ldif.Entry) int {
+		ka, kb := key(a), key(b)
+		return cmp.Or(cmp.Compare(len(ka), len(kb)), strings.Compare(ka, kb))
+	})
+	var records []*ldif.Entry
+	for _, e := range entries {
+		record, err := convert(e)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func writeRecords(w io.Writer, format string, entries []*ldif.Entry, mapping *ldif.CSVMapping, fold int) error {
+	switch format {
+	case "json":
+		data, err := ldif.ToJSON(entries)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "csv":
+		content, err := contentEntries(entries)
+		if err != nil {
+			return fmt.Errorf("CSV output supports content records only: %s", err)
+		}
+		if len(mapping.Columns) == 0 {
+			m := *mapping
+			seen := make(map[string]bool)
+			for _, e := range content {
+				for _, a := range e.Attributes {
+					if key := strings.ToLower(a.Name); !seen[key] {
+						seen[key] = true
+						m.Columns = append(m.Columns, ldif.CSVColumn{Name: a.Name})
+					}
+				}
+			}
+			mapping = &m
+		}
+		return ldif.MarshalCSV(w, content, mapping)
+	}
+	l := &ldif.LDIF{Entries: entries, Version: 1, FoldWidth: fold}
+	return ldif.MarshalStreaming(l, w)
+}
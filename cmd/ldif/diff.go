@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/go-ldap/ldif"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	args:    "old new",
+	summary: "write the change records which turn the entries of one LDIF into another",
+}
+
+func init() {
+	diffCommand.run = runDiff
+	register(diffCommand)
+}
+
+// runDiff writes the change records returned by ldif.Diff as LDIF. With
+// -exit-code it exits with exitFindings if there are any.
+func runDiff(e *env, args []string) int {
+	fs := e.flags(diffCommand)
+	template := parserFlags(fs)
+	opts := &ldif.DiffOptions{}
+	fs.BoolVar(&opts.IgnoreOperational, "ignore-operational", false, "do not compare operational attributes")
+	ignore := fs.String("ignore", "", "comma separated `attributes` which are not compared")
+	fs.BoolVar(&opts.ValuesAsSets, "sets", false, "compare values as sets, write changed values as add and delete instead of replace")
+	exitCode := fs.Bool("exit-code", false, "exit with 1 if there are differences")
+	output := fs.String("o", "", "output `file`, default stdout, compressed by its extension")
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	oldName, newName := fs.Arg(0), fs.Arg(1)
+	if oldName == "-" && newName == "-" {
+		e.errorf("only one input can be stdin")
+		return exitUsage
+	}
+	opts.IgnoreAttributes = splitList(*ignore)
+
+	oldFile, err := e.open(oldName)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	defer oldFile.Close()
+	newFile, err := e.open(newName)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	defer newFile.Close()
+	w, err := e.create(*output)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+
+	changes := 0
+	l := &ldif.LDIF{Version: 1, FoldWidth: *fold}
+	for change, err := range ldif.Diff(
+		ldif.UnmarshalEntries(oldFile, parser(template)),
+		ldif.UnmarshalEntries(newFile, parser(template)),
+		opts,
+	) {
+		if err != nil {
+			w.Close()
+			e.errorf("%s", err)
+			return exitError
+		}
+		l.Entries = []*ldif.Entry{change}
+		if err := ldif.MarshalStreaming(l, w); err != nil {
+			w.Close()
+			e.errorf("%s", err)
+			return exitError
+		}
+		l.Version = 0
+		changes++
+	}
+	if err := w.Close(); err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	if *exitCode && changes > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
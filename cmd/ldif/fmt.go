@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var fmtCommand = &command{
+	name:    "fmt",
+	args:    "[file ...]",
+	summary: "reformat LDIF files with consistent folding, encoding and attribute order",
+}
+
+func init() {
+	fmtCommand.run = runFmt
+	register(fmtCommand)
+}
+
+// runFmt parses each input and writes it back with Marshal: values are
+// base64 encoded only where needed, lines are folded at the given width and
+// the attributes of each record are sorted (objectClass first). Comments
+// and controls are not kept, so -w refuses to overwrite files which have
+// any, and files in UTF-16 (ldifde -u), which would be written as UTF-8. With -canonical, the content records are canonicalized with
+// ldif.Canonicalize.
+func runFmt(e *env, args []string) int {
+	fs := e.flags(fmtCommand)
+	template := parserFlags(fs)
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	list := fs.Bool("l", false, "list the files whose formatting differs, exit with 1 if there are any")
+	canonical := fs.Bool("canonical", false, "merge duplicate entries, attributes and values and sort entries and values, content records only")
+	operationalLast := fs.Bool("operational-last", false, "write the operational attributes after all other attributes, like slapcat")
+	caseIgnore := fs.String("case-ignore", "", "comma separated `attributes` whose values are compared case-insensitively with -canonical, default objectClass, cn, sn, givenName, ou, o, dc, uid, mail, l, st and c")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	code := exitOK
+	for _, name := range inputNames(fs.Args()) {
+		if *write && name == "-" {
+			e.errorf("cannot use -w with stdin")
+			return exitUsage
+		}
+		data, err := e.readAll(name)
+		if err != nil {
+			e.errorf("%s", err)
+			code = exitError
+			continue
+		}
+		// ldifde -u writes UTF-16LE, which Marshal cannot write and
+		// lostLines cannot read
+		if *write && bytes.HasPrefix(data, []byte{0xff, 0xfe}) {
+			e.errorf("%s: not written, the UTF-16 input would be rewritten as UTF-8", displayName(name))
+			code = exitError
+			continue
+		}
+		if lost := lostLines(data); *write && lost != "" {
+			e.errorf("%s: not written, the %s would be lost", displayName(name), lost)
+			code = exitError
+			continue
+		}
+		l := parser(template)
+		if err := ldif.Unmarshal(bytes.NewReader(data), l); err != nil {
+			e.errorf("%s: %s", displayName(name), err)
+			code = exitError
+			continue
+		}
+		if *canonical {
+			err = canonicalize(l, &ldif.CanonicalOptions{CaseIgnore: splitList(*caseIgnore)})
+		} else {
+			sortAttributes(l.Entries)
+		}
+		if err != nil {
+			e.errorf("%s: %s", displayName(name), err)
+			code = exitError
+			continue
+		}
+		l.FoldWidth = *fold
+		l.OperationalLast = *operationalLast
+		out, err := ldif.Marshal(l)
+		if err != nil {
+			e.errorf("%s: %s", displayName(name), err)
+			code = exitError
+			continue
+		}
+
+		switch {
+		case *list:
+			if out != string(data) {
+				e.stdout.Write([]byte(displayName(name) + "\n"))
+				if code == exitOK {
+					code = exitFindings
+				}
+			}
+		case *write:
+			if out == string(data) {
+				continue
+			}
+			w, err := e.create(name)
+			if err == nil {
+				_, err = w.Write([]byte(out))
+				if cerr := w.Close(); err == nil {
+					err = cerr
+				}
+			}
+			if err != nil {
+				e.errorf("%s", err)
+				code = exitError
+			}
+		default:
+			if _, err := e.stdout.Write([]byte(out)); err != nil {
+				e.errorf("%s", err)
+				return exitError
+			}
+		}
+	}
+	return code
+}
+
+// lostLines returns which of comments and controls the LDIF in data has,
+// Marshal does not write either of them.
+func lostLines(data []byte) string {
+	var comments, controls bool
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("#")):
+			comments = true
+		case len(line) >= len("control:") && bytes.EqualFold(line[:len("control:")], []byte("control:")):
+			controls = true
+		}
+	}
+	switch {
+	case comments && controls:
+		return "comments and controls"
+	case comments:
+		return "comments"
+	case controls:
+		return "controls"
+	}
+	return ""
+}
+
+// canonicalize replaces the records of l with their canonical form, it
+// fails for change records.
+func canonicalize(l *ldif.LDIF, opts *ldif.CanonicalOptions) error {
+	entries := make([]*ldap.Entry, len(l.Entries))
+	for i, e := range l.Entries {
+		if e.Entry == nil {
+			return fmt.Errorf("%s is a change record, -canonical needs content records", e.DN())
+		}
+		entries[i] = e.Entry
+	}
+	entries, err := ldif.Canonicalize(entries, opts)
+	if err != nil {
+		return err
+	}
+	l.Entries = make([]*ldif.Entry, len(entries))
+	for i, e := range entries {
+		l.Entries[i] = &ldif.Entry{Entry: e}
+	}
+	return nil
+}
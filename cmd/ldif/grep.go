@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var grepCommand = &command{
+	name:    "grep",
+	args:    "filter [file ...]",
+	summary: "print the entries matching an LDAP search filter",
+}
+
+func init() {
+	grepCommand.run = runGrep
+	register(grepCommand)
+}
+
+var scopes = map[string]int{
+	"base": ldap.ScopeBaseObject,
+	"one":  ldap.ScopeSingleLevel,
+	"sub":  ldap.ScopeWholeSubtree,
+}
+
+// runGrep writes the entries found by ldif.Search as LDIF. Like grep, it
+// exits with exitFindings if no entry matched.
+func runGrep(e *env, args []string) int {
+	fs := e.flags(grepCommand)
+	template := parserFlags(fs)
+	base := fs.String("b", "", "base `DN` of the search, default all entries")
+	scope := fs.String("s", "sub", "`scope` of the search: base, one or sub")
+	attrs := fs.String("a", "", "comma separated `attributes` to print, \"*\" for all user and \"+\" for all operational attributes, default all")
+	limit := fs.Int("z", 0, "stop after `n` entries of each file, 0 for no limit")
+	count := fs.Bool("c", false, "only print the number of matching entries")
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	s, ok := scopes[*scope]
+	if !ok {
+		e.errorf("invalid scope %q, must be base, one or sub", *scope)
+		return exitUsage
+	}
+	req := &ldap.SearchRequest{BaseDN: *base, Scope: s, SizeLimit: *limit, Filter: fs.Arg(0), Attributes: splitList(*attrs)}
+	if _, err := ldif.NewFilter(req.Filter); err != nil {
+		e.errorf("invalid filter %q: %s", req.Filter, err)
+		return exitUsage
+	}
+	if _, err := ldap.ParseDN(req.BaseDN); err != nil {
+		e.errorf("invalid base DN %q: %s", req.BaseDN, err)
+		return exitUsage
+	}
+
+	found := 0
+	l := &ldif.LDIF{Version: 1, FoldWidth: *fold}
+	for _, name := range inputNames(fs.Args()[1:]) {
+		r, err := e.open(name)
+		if err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+		for entry, err := range ldif.Search(r, parser(template), req) {
+			if err != nil {
+				r.Close()
+				e.errorf("%s: %s", displayName(name), err)
+				return exitError
+			}
+			found++
+			if *count {
+				continue
+			}
+			l.Entries = []*ldif.Entry{{Entry: entry}}
+			if err := ldif.MarshalStreaming(l, e.stdout); err != nil {
+				r.Close()
+				e.errorf("%s", err)
+				return exitError
+			}
+			l.Version = 0
+		}
+		r.Close()
+	}
+	if *count {
+		fmt.Fprintln(e.stdout, found)
+	}
+	if found == 0 {
+		return exitFindings
+	}
+	return exitOK
+}
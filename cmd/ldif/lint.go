@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-ldap/ldif"
+)
+
+var lintCommand = &command{
+	name:    "lint",
+	args:    "[file ...]",
+	summary: "report all parse and validation errors of LDIF files",
+}
+
+func init() {
+	lintCommand.run = runLint
+	register(lintCommand)
+}
+
+// runLint prints the problems found by ldif.Lint as "file:line: message",
+// it exits with exitFindings if there are any.
+func runLint(e *env, args []string) int {
+	fs := e.flags(lintCommand)
+	template := parserFlags(fs)
+	quiet := fs.Bool("q", false, "do not print the problems, only set the exit code")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	code := exitOK
+	for _, name := range inputNames(fs.Args()) {
+		r, err := e.open(name)
+		if err != nil {
+			e.errorf("%s", err)
+			code = exitError
+			continue
+		}
+		problems := ldif.Lint(r, parser(template))
+		r.Close()
+		if len(problems) == 0 {
+			continue
+		}
+		if code == exitOK {
+			code = exitFindings
+		}
+		if *quiet {
+			continue
+		}
+		for _, p := range problems {
+			msg := p.Message
+			if p.DN != "" {
+				msg += fmt.Sprintf(" (dn: %s)", p.DN)
+			}
+			fmt.Fprintf(e.stdout, "%s:%d: %s\n", displayName(name), p.Line, msg)
+		}
+	}
+	return code
+}
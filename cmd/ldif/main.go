@@ -0,0 +1,305 @@
+// Command ldif formats, checks, searches, compares, splits, merges and
+// converts LDIF files.
+//
+// Usage:
+//
+//	ldif <command> [flags] [file ...]
+//
+// The commands are:
+//
+//	fmt        reformat LDIF files
+//	lint       report all errors in LDIF files
+//	convert    convert between LDIF, JSON and CSV
+//	diff       write the changes between two LDIF files
+//	grep       print the entries matching a search filter
+//	merge      merge the entries of several LDIF files
+//	refcheck   report dangling references between entries
+//	split      split an LDIF file by subtree, record count or size
+//	stats      print statistics of the records of LDIF files
+//	transform  rewrite records with the rules of a rule file
+//
+// Run "ldif <command> -h" for the flags of a command. The input is read from
+// the given files or from stdin if no file (or "-") is given. Compressed
+// files are decompressed transparently.
+//
+// The exit code is 0 on success, 1 if problems or differences were found
+// (e.g. by lint) or if grep found no entry, 2 for a usage error and 3 for any
+// other error.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitUsage    = 2
+	exitError    = 3
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(e *env, args []string) int
+}
+
+var commands []*command
+
+func register(c *command) {
+	commands = append(commands, c)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+}
+
+// env holds the standard streams of a run, so commands can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 {
+		e.usage()
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		e.usage()
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(e, args[1:])
+		}
+	}
+	e.errorf("unknown command %q", args[0])
+	e.usage()
+	return exitUsage
+}
+
+func (e *env) usage() {
+	fmt.Fprintln(e.stderr, "usage: ldif <command> [flags] [file ...]")
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, "The commands are:")
+	fmt.Fprintln(e.stderr)
+	for _, c := range commands {
+		fmt.Fprintf(e.stderr, "\t%-10s %s\n", c.name, c.summary)
+	}
+}
+
+func (e *env) errorf(format string, args ...any) {
+	fmt.Fprintf(e.stderr, "ldif: "+format+"\n", args...)
+}
+
+// flags returns the flag set for the command, its errors and usage are
+// written to stderr.
+func (e *env) flags(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: ldif %s [flags] %s\n\n%s.\n\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags, it returns false and the exit code if the
+// command must not run.
+func parseFlags(fs *flag.FlagSet, args []string) (bool, int) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, exitOK
+		}
+		return false, exitUsage
+	}
+	return true, exitOK
+}
+
+// inputNames returns the file names given as arguments, "-" is stdin.
+func inputNames(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
+
+// splitList returns the non-empty elements of a comma separated list.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// splitDNs splits a semicolon separated list of DNs, which contain commas
+// themselves, and trims the spaces around the DNs.
+func splitDNs(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// displayName returns the name of an input for messages.
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// open opens the named input, "-" is stdin. Compressed input is
+// decompressed.
+func (e *env) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return ldif.NewReader(e.stdin, ldif.CompressionAuto)
+	}
+	return ldif.OpenFile(name)
+}
+
+// readAll reads the named input.
+func (e *env) readAll(name string) ([]byte, error) {
+	r, err := e.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// create creates the named output, "-" or "" is stdout. The output is
+// compressed as given by the extension of the file name.
+func (e *env) create(name string) (io.WriteCloser, error) {
+	if name == "" || name == "-" {
+		return nopWriteCloser{e.stdout}, nil
+	}
+	return ldif.CreateFile(name, nil)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// modeFlag is a flag.Value for the parser mode.
+type modeFlag struct {
+	mode *ldif.Mode
+}
+
+func (f modeFlag) String() string {
+	if f.mode == nil {
+		return ldif.ModeDefault.String()
+	}
+	return f.mode.String()
+}
+
+func (f modeFlag) Set(s string) error {
+	for _, m := range []ldif.Mode{ldif.ModeDefault, ldif.ModeStrict, ldif.ModeLenient} {
+		if m.String() == s {
+			*f.mode = m
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid mode %q, must be default, strict or lenient", s)
+}
+
+// profileFlag is a flag.Value for the profile.
+type profileFlag struct {
+	profile *ldif.Profile
+}
+
+func (f profileFlag) String() string {
+	if f.profile == nil {
+		return ldif.ProfileDefault.String()
+	}
+	return f.profile.String()
+}
+
+func (f profileFlag) Set(s string) error {
+	for _, p := range []ldif.Profile{ldif.ProfileDefault, ldif.ProfileActiveDirectory} {
+		if p.String() == s {
+			*f.profile = p
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid profile %q, must be default or ad", s)
+}
+
+// parserFlags adds the flags for the parser options to fs.
+func parserFlags(fs *flag.FlagSet) *ldif.LDIF {
+	l := &ldif.LDIF{}
+	fs.Var(modeFlag{&l.Mode}, "mode", "parser `mode`: default, strict or lenient")
+	fs.Var(profileFlag{&l.Profile}, "profile", "`profile` of the LDIF tool which wrote the input: default or ad (Active Directory ldifde), fmt writes it in that style, too")
+	fs.BoolVar(&l.Controls, "controls", false, "parse controls instead of ignoring them")
+	return l
+}
+
+// parser returns a new LDIF with the options of the template.
+func parser(template *ldif.LDIF) *ldif.LDIF {
+	return &ldif.LDIF{Mode: template.Mode, Controls: template.Controls, Limits: template.Limits, Profile: template.Profile}
+}
+
+// sortAttributes sorts the attributes of content and add records by name,
+// with objectClass first. The order of the values is kept.
+func sortAttributes(entries []*ldif.Entry) {
+	less := func(a, b string) bool {
+		ao, bo := strings.EqualFold(a, "objectClass"), strings.EqualFold(b, "objectClass")
+		if ao != bo {
+			return ao
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	}
+	for _, e := range entries {
+		switch {
+		case e.Entry != nil:
+			attrs := e.Entry.Attributes
+			sort.SliceStable(attrs, func(i, j int) bool { return less(attrs[i].Name, attrs[j].Name) })
+		case e.Add != nil:
+			attrs := e.Add.Attributes
+			sort.SliceStable(attrs, func(i, j int) bool { return less(attrs[i].Type, attrs[j].Type) })
+		}
+	}
+}
+
+// contentEntries returns the content records, add records are converted.
+// An error is returned for other change records.
+func contentEntries(entries []*ldif.Entry) ([]*ldap.Entry, error) {
+	var result []*ldap.Entry
+	for _, e := range entries {
+		switch {
+		case e.Entry != nil:
+			result = append(result, e.Entry)
+		case e.Add != nil:
+			attrs := make(map[string][]string, len(e.Add.Attributes))
+			for _, a := range e.Add.Attributes {
+				attrs[a.Type] = append(attrs[a.Type], a.Vals...)
+			}
+			result = append(result, ldap.NewEntry(e.Add.DN, attrs))
+		default:
+			return nil, fmt.Errorf("%s is not a content or add record", e.DN())
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+// runTest runs the command with the given stdin and returns the exit code,
+// stdout and stderr.
+func runTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var unformatted = `dn: cn=foo,dc=example,dc=org
+sn: Foo
+cn:: Zm9v
+objectClass: person
+description: a long description which has to be folded because it is longer than the fold width
+
+`
+
+var formatted = `dn: cn=foo,dc=example,dc=org
+objectClass: person
+cn: foo
+description: a long description which has to be folded because it is longer 
+ than the fold width
+sn: Foo
+
+`
+
+func TestRunUsage(t *testing.T) {
+	if code, _, _ := runTest(t, ""); code != exitUsage {
+		t.Errorf("no command: got exit code %d", code)
+	}
+	if code, _, stderr := runTest(t, "", "nope"); code != exitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("unknown command: got exit code %d, %q", code, stderr)
+	}
+	if code, _, _ := runTest(t, "", "fmt", "-nope"); code != exitUsage {
+		t.Errorf("unknown flag: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, "", "fmt", "-h"); code != exitOK {
+		t.Errorf("help: got exit code %d", code)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	code, stdout, stderr := runTest(t, unformatted, "fmt")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout != formatted {
+		t.Errorf("got:\n%s\nexpected:\n%s", stdout, formatted)
+	}
+
+	path := writeFile(t, "foo.ldif", unformatted)
+	if code, stdout, _ := runTest(t, "", "fmt", "-l", path); code != exitFindings || stdout != path+"\n" {
+		t.Errorf("-l: got exit code %d, %q", code, stdout)
+	}
+	if code, _, stderr := runTest(t, "", "fmt", "-w", path); code != exitOK {
+		t.Fatalf("-w: got exit code %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != formatted {
+		t.Errorf("-w wrote:\n%s", data)
+	}
+	if code, stdout, _ := runTest(t, "", "fmt", "-l", path); code != exitOK || stdout != "" {
+		t.Errorf("-l after -w: got exit code %d, %q", code, stdout)
+	}
+
+	for _, in := range []string{
+		"# a comment\n" + unformatted,
+		"dn: cn=foo,dc=example,dc=org\nControl: 1.2.840.113556.1.4.805 true\nchangetype: delete\n",
+	} {
+		path := writeFile(t, "lost.ldif", in)
+		if code, _, stderr := runTest(t, "", "fmt", "-w", path); code != exitError || !strings.Contains(stderr, "would be lost") {
+			t.Errorf("-w with %q: got exit code %d: %s", in, code, stderr)
+		}
+		if data, _ := os.ReadFile(path); string(data) != in {
+			t.Errorf("-w overwrote %q:\n%s", in, data)
+		}
+	}
+
+	// UTF-16LE with a byte order mark, like ldifde -u
+	var utf16 []byte
+	for _, r := range "\ufeff# a comment\r\ndn: CN=foo,DC=example,DC=com\r\ncn: foo\r\n" {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+	path = writeFile(t, "export.ldf", string(utf16))
+	if code, _, stderr := runTest(t, "", "fmt", "-profile", "ad", "-w", path); code != exitError || !strings.Contains(stderr, "UTF-16") {
+		t.Errorf("-w with UTF-16: got exit code %d: %s", code, stderr)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(utf16) {
+		t.Errorf("-w overwrote the UTF-16 file:\n%q", data)
+	}
+
+	if code, _, _ := runTest(t, "dn: cn=foo\ncn foo\n", "fmt"); code != exitError {
+		t.Errorf("invalid input: got exit code %d", code)
+	}
+
+	code, stdout, _ = runTest(t, "dn: CN=foo,DC=example,DC=com\ncn: foo\n", "fmt", "-profile", "ad")
+	if code != exitOK || stdout != "dn: CN=foo,DC=example,DC=com\r\nchangetype: add\r\ncn: foo\r\n\r\n" {
+		t.Errorf("-profile ad: got exit code %d: %q", code, stdout)
+	}
+
+	in := "dn: cn=foo,dc=example,dc=org\nobjectClass: person\nentryUUID: 1234\ncn: foo\n"
+	code, stdout, _ = runTest(t, in, "fmt", "-operational-last")
+	if code != exitOK || stdout != "dn: cn=foo,dc=example,dc=org\nobjectClass: person\ncn: foo\nentryUUID: 1234\n\n" {
+		t.Errorf("-operational-last: got exit code %d:\n%s", code, stdout)
+	}
+}
+
+func TestFmtCanonical(t *testing.T) {
+	in := "dn: cn=foo,dc=example,dc=org\ncn: foo\nmail: B@example.org\n\ndn: dc=example,dc=org\ndc: example\n\ndn: CN=Foo,dc=example,dc=org\nmail: b@example.org\nMail: a@example.org\n"
+	expected := "dn: dc=example,dc=org\ndc: example\n\ndn: cn=foo,dc=example,dc=org\ncn: foo\nmail: B@example.org\nmail: a@example.org\n\n"
+	code, stdout, stderr := runTest(t, in, "fmt", "-canonical")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", stdout, expected)
+	}
+	if code, stdout, _ := runTest(t, in, "fmt", "-canonical", "-case-ignore", "cn"); code != exitOK || !strings.Contains(stdout, "mail: b@example.org\n") {
+		t.Errorf("-case-ignore: got exit code %d:\n%s", code, stdout)
+	}
+	if code, _, _ := runTest(t, "dn: cn=foo\nchangetype: delete\n", "fmt", "-canonical"); code != exitError {
+		t.Errorf("change record: got exit code %d", code)
+	}
+}
+
+func TestLint(t *testing.T) {
+	if code, stdout, _ := runTest(t, formatted, "lint"); code != exitOK || stdout != "" {
+		t.Errorf("valid input: got exit code %d, %q", code, stdout)
+	}
+
+	in := "dn: cn=foo,dc=example,dc=org\ncn foo\n\ndn: cn=bar,,dc=example\ncn: bar\n"
+	code, stdout, _ := runTest(t, in, "lint")
+	if code != exitFindings {
+		t.Errorf("got exit code %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "<stdin>:3: ") || !strings.HasPrefix(lines[1], "<stdin>:4: ") {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+
+	if code, stdout, _ := runTest(t, "dn: cn=foo\ncn: foo\n", "lint", "-mode", "strict", "-q"); code != exitFindings || stdout != "" {
+		t.Errorf("strict: got exit code %d, %q", code, stdout)
+	}
+	if code, _, _ := runTest(t, "", "lint", "/does/not/exist.ldif"); code != exitError {
+		t.Errorf("missing file: got exit code %d", code)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	code, jsonOut, stderr := runTest(t, formatted, "convert", "-to", "json")
+	if code != exitOK {
+		t.Fatalf("to json: got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(jsonOut, `"dn": "cn=foo,dc=example,dc=org"`) {
+		t.Errorf("unexpected JSON:\n%s", jsonOut)
+	}
+
+	code, ldifOut, stderr := runTest(t, jsonOut, "convert", "-from", "json")
+	if code != exitOK {
+		t.Fatalf("from json: got exit code %d: %s", code, stderr)
+	}
+	if ldifOut != "version: 1\n"+formatted {
+		t.Errorf("round trip through JSON:\n%s", ldifOut)
+	}
+
+	code, csvOut, stderr := runTest(t, formatted, "convert", "-to", "csv")
+	if code != exitOK {
+		t.Fatalf("to csv: got exit code %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(csvOut, "dn,objectClass,cn,description,sn\n") {
+		t.Errorf("unexpected CSV:\n%s", csvOut)
+	}
+
+	path := writeFile(t, "people.csv", "uid,cn,mail\njdoe,John Doe,jdoe@example.org|john@example.org\n")
+	code, ldifOut, stderr = runTest(t, "", "convert", "-dn", "uid={uid},ou=People,dc=example,dc=org", path)
+	if code != exitOK {
+		t.Fatalf("from csv: got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(ldifOut, "dn: uid=jdoe,ou=People,dc=example,dc=org\n") || !strings.Contains(ldifOut, "mail: john@example.org\n") {
+		t.Errorf("unexpected LDIF from CSV:\n%s", ldifOut)
+	}
+	if code, _, _ := runTest(t, "", "convert", path); code != exitError {
+		t.Errorf("csv without DN template: got exit code %d", code)
+	}
+
+	if code, _, _ := runTest(t, "dn: cn=foo\nchangetype: delete\n", "convert", "-to", "csv"); code != exitError {
+		t.Errorf("change records to csv: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, "", "convert", "-to", "xml"); code != exitUsage {
+		t.Errorf("invalid format: got exit code %d", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := writeFile(t, "old.ldif", "dn: cn=foo,dc=example,dc=org\ncn: foo\nsn: Foo\nmodifyTimestamp: 20240101000000Z\n\ndn: cn=bar,dc=example,dc=org\ncn: bar\n")
+	new := writeFile(t, "new.ldif", "dn: cn=foo,dc=example,dc=org\ncn: foo\nsn: Bar\nmodifyTimestamp: 20250101000000Z\n")
+
+	expected := "version: 1\ndn: cn=foo,dc=example,dc=org\nchangetype: modify\nreplace: sn\nsn: Bar\n-\n\ndn: cn=bar,dc=example,dc=org\nchangetype: delete\n\n"
+	code, stdout, stderr := runTest(t, "", "diff", "-ignore-operational", old, new)
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", stdout, expected)
+	}
+	if code, _, _ := runTest(t, "", "diff", "-exit-code", old, new); code != exitFindings {
+		t.Errorf("-exit-code with differences: got exit code %d", code)
+	}
+	if code, stdout, _ := runTest(t, "", "diff", "-exit-code", old, old); code != exitOK || stdout != "" {
+		t.Errorf("-exit-code without differences: got exit code %d, %q", code, stdout)
+	}
+
+	if code, _, _ := runTest(t, "", "diff", old); code != exitUsage {
+		t.Errorf("one file: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, "", "diff", "-", "-"); code != exitUsage {
+		t.Errorf("stdin twice: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, "dn: cn=foo\nchangetype: delete\n", "diff", old, "-"); code != exitError {
+		t.Errorf("change records: got exit code %d", code)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	in := "dn: ou=People,dc=example,dc=org\nou: People\n\ndn: uid=jdoe,ou=People,dc=example,dc=org\nuid: jdoe\nloginShell: /bin/bash\n\ndn: uid=asmith,ou=People,dc=example,dc=org\nuid: asmith\nloginShell: /bin/zsh\n"
+
+	code, stdout, stderr := runTest(t, in, "grep", "-a", "uid", "(!(loginShell=/bin/bash))")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	expected := "version: 1\ndn: ou=People,dc=example,dc=org\n\ndn: uid=asmith,ou=People,dc=example,dc=org\nuid: asmith\n\n"
+	if stdout != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", stdout, expected)
+	}
+	if code, stdout, _ := runTest(t, in, "grep", "-c", "-b", "ou=people,dc=example,dc=org", "-s", "one", "(uid=*)"); code != exitOK || stdout != "2\n" {
+		t.Errorf("-c: got exit code %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runTest(t, in, "grep", "(uid=nobody)"); code != exitFindings || stdout != "" {
+		t.Errorf("no match: got exit code %d, %q", code, stdout)
+	}
+
+	if code, _, _ := runTest(t, in, "grep"); code != exitUsage {
+		t.Errorf("no filter: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, in, "grep", "uid=jdoe"); code != exitUsage {
+		t.Errorf("invalid filter: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, in, "grep", "-s", "tree", "(uid=jdoe)"); code != exitUsage {
+		t.Errorf("invalid scope: got exit code %d", code)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	in := "dn: uid=jdoe,dc=old,dc=com\nuid: jdoe\nmail: JDoe@Example.org\ncreateTimestamp: 20240101000000Z\n"
+	expected := "version: 1\ndn: uid=jdoe,dc=new,dc=org\nmail: jdoe@example.org\nuid: jdoe\n\n"
+
+	yamlRules := writeFile(t, "rules.yaml", `
+- op: drop
+  attributes: ["+"]
+- op: map
+  attribute: mail
+  func: lower
+- op: rewriteDN
+  from: dc=old,dc=com
+  to: dc=new,dc=org
+`)
+	code, stdout, stderr := runTest(t, in, "transform", "-rules", yamlRules)
+	if code != exitOK {
+		t.Fatalf("yaml: got exit code %d: %s", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("yaml: got:\n%s\nexpected:\n%s", stdout, expected)
+	}
+
+	jsonRules := writeFile(t, "rules.json", `[{"op": "drop", "attributes": ["+"]}, {"op": "map", "attribute": "mail", "func": "lower"},
+		{"op": "rewriteDN", "from": "dc=old,dc=com", "to": "dc=new,dc=org"}]`)
+	if code, stdout, _ := runTest(t, in, "transform", "-rules", jsonRules); code != exitOK || stdout != expected {
+		t.Errorf("json: got exit code %d:\n%s", code, stdout)
+	}
+
+	if code, _, _ := runTest(t, in, "transform"); code != exitUsage {
+		t.Errorf("no rules: got exit code %d", code)
+	}
+	mixed := in + "\ndn: uid=jsmith,dc=old,dc=com\nchangetype: delete\n"
+	if code, _, stderr := runTest(t, mixed, "transform", "-rules", yamlRules); code != exitError || !strings.Contains(stderr, ldif.ErrMixed.Error()) {
+		t.Errorf("mixed records: got exit code %d: %s", code, stderr)
+	}
+
+	empty := writeFile(t, "empty.yaml", "- op: drop\n  attributes: [\"+\"]\n-\n")
+	if code, _, stderr := runTest(t, in, "transform", "-rules", empty); code != exitError || !strings.Contains(stderr, "rule 2: null rule") {
+		t.Errorf("empty rule: got exit code %d: %s", code, stderr)
+	}
+	invalid := writeFile(t, "invalid.yaml", "- op: nope\n")
+	if code, _, _ := runTest(t, in, "transform", "-rules", invalid); code != exitError {
+		t.Errorf("invalid rules: got exit code %d", code)
+	}
+}
+
+func TestRefcheck(t *testing.T) {
+	in := "dn: dc=example,dc=org\ndc: example\n\ndn: cn=admins,dc=example,dc=org\ncn: admins\nmember: uid=gone,dc=example,dc=org\nmember: cn=svc,o=external\n"
+	path := writeFile(t, "groups.ldif", in)
+	fix := filepath.Join(t.TempDir(), "fix.ldif")
+
+	code, stdout, stderr := runTest(t, "", "refcheck", "-external", "o=external", "-fix", fix, path)
+	if code != exitFindings {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout != path+`:4: member "uid=gone,dc=example,dc=org" of "cn=admins,dc=example,dc=org" does not exist`+"\n" {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+	data, err := os.ReadFile(fix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "delete: member\nmember: uid=gone,dc=example,dc=org\n-\n") {
+		t.Errorf("unexpected fixes:\n%s", data)
+	}
+
+	// suffixes with several RDNs
+	in2 := in + "member: cn=svc,o=partner,c=de\nmember: cn=ghost,o=other,c=de\n"
+	code, stdout, _ = runTest(t, in2, "refcheck", "-external", "o=external; o=partner,c=de")
+	if code != exitFindings || strings.Count(stdout, "\n") != 2 || !strings.Contains(stdout, `"uid=gone,dc=example,dc=org"`) ||
+		!strings.Contains(stdout, `"cn=ghost,o=other,c=de"`) {
+		t.Errorf("multi-RDN suffixes: got exit code %d:\n%s", code, stdout)
+	}
+
+	if code, stdout, _ := runTest(t, in, "refcheck", "-q"); code != exitFindings || stdout != "" {
+		t.Errorf("stdin: got exit code %d, %q", code, stdout)
+	}
+	if code, _, _ := runTest(t, in, "refcheck", "-attrs", "manager"); code != exitOK {
+		t.Errorf("-attrs: got exit code %d", code)
+	}
+	if code, _, _ := runTest(t, "", "refcheck", path, path); code != exitUsage {
+		t.Errorf("two files: got exit code %d", code)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	in := "dn: dc=example,dc=org\ndc: example\n\ndn: ou=People,dc=example,dc=org\nou: People\n\ndn: uid=jdoe,ou=People,dc=example,dc=org\nuid: jdoe\n"
+	path := writeFile(t, "export.ldif", in)
+	prefix := strings.TrimSuffix(path, ".ldif")
+
+	code, stdout, stderr := runTest(t, "", "split", "-records", "2", path)
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout != prefix+"-0001.ldif\n"+prefix+"-0002.ldif\n" {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+	data, err := os.ReadFile(prefix + "-0002.ldif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "version: 1\ndn: uid=jdoe,ou=People,dc=example,dc=org\nuid: jdoe\n\n" {
+		t.Errorf("unexpected second part:\n%s", data)
+	}
+
+	out := filepath.Join(t.TempDir(), "tree")
+	code, stdout, stderr = runTest(t, in, "split", "-subtree", "ou=People,dc=example,dc=org", "-prefix", out, "-ext", ".ldif.gz")
+	if code != exitOK {
+		t.Fatalf("-subtree: got exit code %d: %s", code, stderr)
+	}
+	if stdout != out+"-rest-0001.ldif.gz\n"+out+"-ou_people_dc_example_dc_org-0001.ldif.gz\n" {
+		t.Errorf("-subtree: unexpected output:\n%s", stdout)
+	}
+	if code, stdout, _ := runTest(t, "", "grep", "(uid=jdoe)", out+"-ou_people_dc_example_dc_org-0001.ldif.gz"); code != exitOK || !strings.Contains(stdout, "uid: jdoe") {
+		t.Errorf("-subtree: unexpected part: %d, %q", code, stdout)
+	}
+
+	code, _, stderr = runTest(t, in, "split", "-subtree", "ou=a-b,dc=example,dc=org;ou=a_b,dc=example,dc=org", "-prefix", out)
+	if code != exitUsage || !strings.Contains(stderr, `same file name part "ou_a_b_dc_example_dc_org"`) {
+		t.Errorf("colliding subtrees: got exit code %d: %s", code, stderr)
+	}
+	if code, _, _ := runTest(t, in, "split", "-size", "1x"); code != exitUsage {
+		t.Errorf("invalid size: got exit code %d", code)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := writeFile(t, "a.ldif", "dn: cn=foo,dc=example,dc=org\ncn: foo\nmail: foo@example.org\n")
+	b := writeFile(t, "b.ldif", "dn: cn=foo,dc=example,dc=org\ncn: foo\nmail: foo@example.com\n\ndn: cn=bar,dc=example,dc=org\ncn: bar\n")
+
+	code, stdout, stderr := runTest(t, "", "merge", "-policy", "merge", a, b)
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	expected := "version: 1\ndn: cn=foo,dc=example,dc=org\ncn: foo\nmail: foo@example.org\nmail: foo@example.com\n\ndn: cn=bar,dc=example,dc=org\ncn: bar\n\n"
+	if stdout != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	if code, stdout, _ := runTest(t, "", "merge", "-policy", "last", a, b); code != exitOK || !strings.Contains(stdout, "mail: foo@example.com\n") || strings.Contains(stdout, "foo@example.org") {
+		t.Errorf("-policy last: got exit code %d:\n%s", code, stdout)
+	}
+	if code, _, stderr := runTest(t, "", "merge", "-policy", "error", a, b); code != exitError || !strings.Contains(stderr, "duplicate") {
+		t.Errorf("-policy error: got exit code %d: %s", code, stderr)
+	}
+	if code, _, _ := runTest(t, "", "merge", "-policy", "newest", a, b); code != exitUsage {
+		t.Errorf("invalid policy: got exit code %d", code)
+	}
+}
+
+func TestStats(t *testing.T) {
+	in := "dn: dc=example,dc=org\nobjectClass: domain\ndc: example\n\ndn: cn=old,dc=example,dc=org\nchangetype: delete\n"
+	code, stdout, stderr := runTest(t, in, "stats")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "records        2\nentries        1\n") || !strings.Contains(stdout, "  delete  1\n") {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+
+	code, stdout, stderr = runTest(t, in, "stats", "-json")
+	if code != exitOK {
+		t.Fatalf("-json: got exit code %d: %s", code, stderr)
+	}
+	var stats ldif.Stats
+	if err := json.Unmarshal([]byte(stdout), &stats); err != nil {
+		t.Fatalf("-json: %s:\n%s", err, stdout)
+	}
+	if stats.Records != 2 || stats.ObjectClasses["domain"] != 1 {
+		t.Errorf("-json: unexpected output:\n%s", stdout)
+	}
+
+	if code, _, _ := runTest(t, "dn: cn=foo\ncn foo\n", "stats"); code != exitError {
+		t.Errorf("invalid input: got exit code %d", code)
+	}
+}
+
+func TestConvertChangeLogs(t *testing.T) {
+	accesslog := "dn: cn=accesslog\ncn: accesslog\n\n" +
+		"dn: reqStart=20240101000000.000002Z,cn=accesslog\nreqStart: 20240101000000.000002Z\nreqType: delete\nreqDN: uid=jdoe,dc=example,dc=org\nreqResult: 0\n\n" +
+		"dn: reqStart=20240101000000.000001Z,cn=accesslog\nreqStart: 20240101000000.000001Z\nreqType: modify\nreqDN: uid=jdoe,dc=example,dc=org\nreqResult: 0\nreqMod: mail:+ jdoe@example.org\n"
+	expected := "version: 1\ndn: uid=jdoe,dc=example,dc=org\nchangetype: modify\nadd: mail\nmail: jdoe@example.org\n-\n\ndn: uid=jdoe,dc=example,dc=org\nchangetype: delete\n\n"
+	code, stdout, stderr := runTest(t, accesslog, "convert", "-from", "accesslog")
+	if code != exitOK {
+		t.Fatalf("accesslog: got exit code %d: %s", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("accesslog: expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	changelog := "dn: changenumber=10,cn=changelog\nchangeNumber: 10\nchangeType: delete\ntargetDn: uid=jdoe,dc=example,dc=org\n\n" +
+		"dn: changenumber=9,cn=changelog\nchangeNumber: 9\nchangeType: modify\ntargetDn: uid=jdoe,dc=example,dc=org\nchanges:: YWRkOiBtYWlsCm1haWw6IGpkb2VAZXhhbXBsZS5vcmcKLQo=\n"
+	code, stdout, stderr = runTest(t, changelog, "convert", "-from", "changelog")
+	if code != exitOK {
+		t.Fatalf("changelog: got exit code %d: %s", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("changelog: expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	if code, _, _ := runTest(t, accesslog, "convert", "-to", "accesslog"); code != exitUsage {
+		t.Errorf("-to accesslog: got exit code %d", code)
+	}
+}
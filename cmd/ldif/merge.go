@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/go-ldap/ldif"
+)
+
+var mergeCommand = &command{
+	name:    "merge",
+	args:    "file ...",
+	summary: "merge the entries of several LDIF files",
+}
+
+func init() {
+	mergeCommand.run = runMerge
+	register(mergeCommand)
+}
+
+// policyFlag is a flag.Value for the merge policy.
+type policyFlag struct {
+	policy *ldif.MergePolicy
+}
+
+func (f policyFlag) String() string {
+	if f.policy == nil {
+		return ldif.MergeFirstWins.String()
+	}
+	return f.policy.String()
+}
+
+func (f policyFlag) Set(s string) error {
+	for _, p := range []ldif.MergePolicy{ldif.MergeFirstWins, ldif.MergeLastWins, ldif.MergeValues, ldif.MergeError} {
+		if p.String() == s {
+			*f.policy = p
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid policy %q, must be first, last, merge or error", s)
+}
+
+// runMerge writes the entries returned by ldif.Merge for all inputs.
+func runMerge(e *env, args []string) int {
+	fs := e.flags(mergeCommand)
+	template := parserFlags(fs)
+	var policy ldif.MergePolicy
+	fs.Var(policyFlag{&policy}, "policy", "`policy` for entries with the same DN: first, last, merge (the values) or error")
+	output := fs.String("o", "", "output `file`, default stdout, compressed by its extension")
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var inputs []iter.Seq2[*ldif.Entry, error]
+	for _, name := range inputNames(fs.Args()) {
+		r, err := e.open(name)
+		if err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+		defer r.Close()
+		inputs = append(inputs, withName(name, ldif.UnmarshalEntries(r, parser(template))))
+	}
+	entries, err := ldif.Merge(inputs, policy)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+
+	w, err := e.create(*output)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	err = ldif.MarshalStreaming(&ldif.LDIF{Entries: entries, Version: 1, FoldWidth: *fold}, w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// withName prefixes the errors of the records with the name of the input.
+func withName(name string, records iter.Seq2[*ldif.Entry, error]) iter.Seq2[*ldif.Entry, error] {
+	return func(yield func(*ldif.Entry, error) bool) {
+		for entry, err := range records {
+			if err != nil {
+				err = fmt.Errorf("%s: %w", displayName(name), err)
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
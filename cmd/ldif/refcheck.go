@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-ldap/ldif"
+)
+
+var refcheckCommand = &command{
+	name:    "refcheck",
+	args:    "[file]",
+	summary: "report references to entries which are not in an LDIF file",
+}
+
+func init() {
+	refcheckCommand.run = runRefcheck
+	register(refcheckCommand)
+}
+
+// openSeeker opens the named input for reading it twice: an uncompressed
+// file directly, stdin and compressed files are read into memory.
+func (e *env) openSeeker(name string) (io.ReadSeekCloser, error) {
+	if name != "-" && ldif.CompressionFromExtension(name) == ldif.CompressionNone {
+		return os.Open(name)
+	}
+	data, err := e.readAll(name)
+	if err != nil {
+		return nil, err
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// runRefcheck prints the problems found by ldif.CheckIntegrityReader as
+// "file:line: message", it exits with exitFindings if there are any. With
+// -fix, the change records removing the dangling references are written.
+func runRefcheck(e *env, args []string) int {
+	fs := e.flags(refcheckCommand)
+	template := parserFlags(fs)
+	external := fs.String("external", "", "semicolon separated `suffixes` of entries outside the file, references to them are not checked")
+	attrs := fs.String("attrs", "", "comma separated DN-valued `attributes` to check, default member, uniqueMember, manager, owner and memberOf")
+	fix := fs.String("fix", "", "write the change records which remove the dangling references to `file`")
+	quiet := fs.Bool("q", false, "do not print the problems, only set the exit code")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	name := "-"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	opts := &ldif.IntegrityOptions{Attributes: splitList(*attrs), ExternalSuffixes: splitDNs(*external)}
+
+	r, err := e.openSeeker(name)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	problems, err := ldif.CheckIntegrityReader(r, parser(template), opts)
+	r.Close()
+	if err != nil {
+		e.errorf("%s: %s", displayName(name), err)
+		return exitError
+	}
+	if !*quiet {
+		for _, p := range problems {
+			fmt.Fprintf(e.stdout, "%s:%d: %s\n", displayName(name), p.Line, p)
+		}
+	}
+
+	if *fix != "" {
+		w, err := e.create(*fix)
+		if err == nil {
+			err = ldif.MarshalStreaming(&ldif.LDIF{Entries: ldif.IntegrityFixes(problems), Version: 1}, w)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+	}
+	if len(problems) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldif"
+)
+
+var splitCommand = &command{
+	name:    "split",
+	args:    "[file]",
+	summary: "split an LDIF file by subtree, record count or size",
+}
+
+func init() {
+	splitCommand.run = runSplit
+	register(splitCommand)
+}
+
+// parseSize parses a size in bytes with an optional k, M or G suffix.
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// partName returns a file name part for the subtree: its RDN values,
+// lower cased, with all other characters replaced by "_".
+func partName(subtree string) string {
+	if subtree == "" {
+		return "rest"
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(subtree) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// runSplit writes the records of the input to the files
+// "<prefix>-<subtree>-0001<ext>", ... (without -subtree just
+// "<prefix>-0001<ext>") and prints their names.
+func runSplit(e *env, args []string) int {
+	fs := e.flags(splitCommand)
+	template := parserFlags(fs)
+	subtrees := fs.String("subtree", "", "semicolon separated `DNs` of subtrees which get their own files")
+	records := fs.Int("records", 0, "maximum number of `records` per file")
+	size := fs.String("size", "", "maximum `size` of a file in bytes, with an optional k, M or G suffix")
+	prefix := fs.String("prefix", "", "`prefix` of the output files, default the input file name without extension or \"part\" for stdin")
+	ext := fs.String("ext", ".ldif", "`extension` of the output files, e.g. \".ldif.gz\" to compress them")
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	name := "-"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	opts := &ldif.SplitOptions{MaxRecords: *records, FoldWidth: *fold}
+	if *size != "" {
+		n, err := parseSize(*size)
+		if err != nil {
+			e.errorf("%s", err)
+			return exitUsage
+		}
+		opts.MaxBytes = n
+	}
+	opts.Subtrees = splitDNs(*subtrees)
+	// different subtrees can get the same name, their parts would overwrite
+	// each other
+	names := make(map[string]string)
+	for _, subtree := range opts.Subtrees {
+		part := partName(subtree)
+		if other, ok := names[part]; ok {
+			e.errorf("subtrees %q and %q have the same file name part %q", other, subtree, part)
+			return exitUsage
+		}
+		names[part] = subtree
+	}
+	if *prefix == "" {
+		*prefix = "part"
+		if name != "-" {
+			*prefix = strings.TrimSuffix(name, filepath.Ext(name))
+			if ldif.CompressionFromExtension(name) != ldif.CompressionNone {
+				*prefix = strings.TrimSuffix(*prefix, filepath.Ext(*prefix))
+			}
+		}
+	}
+
+	r, err := e.open(name)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	defer r.Close()
+	err = ldif.Split(ldif.UnmarshalEntries(r, parser(template)), opts, func(part ldif.SplitPart) (io.WriteCloser, error) {
+		file := fmt.Sprintf("%s-%04d%s", *prefix, part.Index, *ext)
+		if len(opts.Subtrees) > 0 {
+			file = fmt.Sprintf("%s-%s-%04d%s", *prefix, partName(part.Subtree), part.Index, *ext)
+		}
+		fmt.Fprintln(e.stdout, file)
+		return ldif.CreateFile(file, nil)
+	})
+	if err != nil {
+		e.errorf("%s: %s", displayName(name), err)
+		return exitError
+	}
+	return exitOK
+}
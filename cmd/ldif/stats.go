@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/go-ldap/ldif"
+)
+
+var statsCommand = &command{
+	name:    "stats",
+	args:    "[file ...]",
+	summary: "print statistics of the records of LDIF files",
+}
+
+func init() {
+	statsCommand.run = runStats
+	register(statsCommand)
+}
+
+// runStats prints the statistics of all inputs together, as a text report
+// or with -json as JSON.
+func runStats(e *env, args []string) int {
+	fs := e.flags(statsCommand)
+	template := parserFlags(fs)
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	largest := fs.Int("largest", 10, "number of largest `entries` to list")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	stats := ldif.NewStats(&ldif.StatsOptions{Largest: *largest})
+	for _, name := range inputNames(fs.Args()) {
+		r, err := e.open(name)
+		if err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+		for record, err := range ldif.UnmarshalEntries(r, parser(template)) {
+			if err == nil {
+				err = stats.Add(record)
+			}
+			if err != nil {
+				r.Close()
+				e.errorf("%s: %s", displayName(name), err)
+				return exitError
+			}
+		}
+		r.Close()
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			e.errorf("%s", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if err := stats.WriteText(e.stdout); err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-ldap/ldif"
+	"gopkg.in/yaml.v3"
+)
+
+var transformCommand = &command{
+	name:    "transform",
+	args:    "-rules file [file ...]",
+	summary: "rewrite records with the transforms of a YAML or JSON rule file",
+}
+
+func init() {
+	transformCommand.run = runTransform
+	register(transformCommand)
+}
+
+// readRules reads the rule file, a list of ldif.TransformRule in JSON or,
+// with a .yaml or .yml extension, in YAML.
+func readRules(name string) ([]ldif.Transform, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		var rules any
+		if err := yaml.Unmarshal(data, &rules); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(rules); err != nil {
+			return nil, err
+		}
+	}
+	return ldif.ParseTransformRules(data)
+}
+
+// runTransform streams the records of all inputs through the transforms of
+// the rule file and writes the result as LDIF. As with Marshal, the output
+// cannot mix content and change records.
+func runTransform(e *env, args []string) int {
+	fs := e.flags(transformCommand)
+	template := parserFlags(fs)
+	rules := fs.String("rules", "", "YAML or JSON `file` with the list of transform rules (ldif.TransformRule)")
+	output := fs.String("o", "", "output `file`, default stdout, compressed by its extension")
+	fold := fs.Int("fold", 0, "line `width` for folding, 0 for the default of 76, -1 disables folding")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *rules == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	transforms, err := readRules(*rules)
+	if err != nil {
+		e.errorf("invalid rules %s: %s", *rules, err)
+		return exitError
+	}
+
+	w, err := e.create(*output)
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	l := &ldif.LDIF{Version: 1, FoldWidth: *fold}
+	hasEntry, hasChange := false, false
+	err = func() error {
+		for _, name := range inputNames(fs.Args()) {
+			r, err := e.open(name)
+			if err != nil {
+				return err
+			}
+			for entry, err := range ldif.TransformEntries(ldif.UnmarshalEntries(r, parser(template)), transforms...) {
+				if err == nil {
+					// each record is marshalled on its own, so the mixing
+					// check of MarshalStreaming does not see the others
+					if entry.Entry != nil {
+						hasEntry = true
+					} else {
+						hasChange = true
+					}
+					if hasEntry && hasChange {
+						err = fmt.Errorf("%s: %w", entry.DN(), ldif.ErrMixed)
+					}
+				}
+				if err == nil {
+					l.Entries = []*ldif.Entry{entry}
+					err = ldif.MarshalStreaming(l, w)
+					l.Version = 0
+				}
+				if err != nil {
+					r.Close()
+					return fmt.Errorf("%s: %s", displayName(name), err)
+				}
+			}
+			r.Close()
+		}
+		return nil
+	}()
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		e.errorf("%s", err)
+		return exitError
+	}
+	return exitOK
+}
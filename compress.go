@@ -0,0 +1,246 @@
+package ldif
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression format of an LDIF file.
+type Compression int
+
+// The supported compression formats. With CompressionAuto the format is
+// detected from the magic bytes when reading and from the file extension
+// when creating a file.
+const (
+	CompressionAuto Compression = iota
+	CompressionNone
+	CompressionGzip
+	CompressionBzip2
+	CompressionZstd
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// String returns the name of the compression format.
+func (c Compression) String() string {
+	switch c {
+	case CompressionAuto:
+		return "auto"
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionBzip2:
+		return "bzip2"
+	case CompressionZstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// CompressionFromExtension returns the compression format for the extension
+// of the given file name: ".gz", ".bz2" or ".zst". For all others
+// CompressionNone is returned.
+func CompressionFromExtension(name string) Compression {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".gzip":
+		return CompressionGzip
+	case ".bz2":
+		return CompressionBzip2
+	case ".zst", ".zstd":
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// FileOptions are the options for CreateFile.
+type FileOptions struct {
+	// Compression of the file, CompressionAuto uses the file extension
+	Compression Compression
+	// Perm are the permissions of a new file, default 0644
+	Perm os.FileMode
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type writeCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w *writeCloser) Close() error {
+	var errs []error
+	for _, c := range w.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// NewReader returns a reader which decompresses the data from r. With
+// CompressionAuto the format is detected from the magic bytes at the start
+// of the data, uncompressed data is passed through. Closing the returned
+// reader does not close r.
+func NewReader(r io.Reader, c Compression) (io.ReadCloser, error) {
+	if c == CompressionAuto {
+		br := bufio.NewReader(r)
+		head, err := br.Peek(len(zstdMagic))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		switch {
+		case bytes.HasPrefix(head, gzipMagic):
+			c = CompressionGzip
+		case bytes.HasPrefix(head, bzip2Magic):
+			c = CompressionBzip2
+		case bytes.HasPrefix(head, zstdMagic):
+			c = CompressionZstd
+		default:
+			c = CompressionNone
+		}
+		r = br
+	}
+
+	switch c {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case CompressionZstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression %s", c)
+}
+
+// NewWriter returns a writer which compresses the data written to it into
+// w. The returned writer must be closed to flush the compressed data, this
+// does not close w. Writing bzip2 is not supported.
+func NewWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionNone:
+		return &writeCloser{Writer: w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression %s for writing", c)
+}
+
+// OpenFile opens the given file for reading, a compressed file is
+// transparently decompressed. The format is detected from the magic bytes.
+//
+// A typical use is
+//
+//	fh, err := ldif.OpenFile("export.ldif.gz")
+//	if err != nil {
+//		return err
+//	}
+//	defer fh.Close()
+//	for entry, err := range ldif.UnmarshalEntries(fh, &ldif.LDIF{}) {
+//		...
+//	}
+func OpenFile(path string) (io.ReadCloser, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(fh, CompressionAuto)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: r, closers: []io.Closer{r, fh}}, nil
+}
+
+// CreateFile creates (or truncates) the given file for writing, the data is
+// compressed as given in the options. The returned writer must be closed.
+// If opts is nil, the compression is derived from the file extension.
+func CreateFile(path string, opts *FileOptions) (io.WriteCloser, error) {
+	if opts == nil {
+		opts = &FileOptions{}
+	}
+	c := opts.Compression
+	if c == CompressionAuto {
+		c = CompressionFromExtension(path)
+	}
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	if c == CompressionBzip2 {
+		return nil, fmt.Errorf("unsupported compression %s for writing", c)
+	}
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(fh, c)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return &writeCloser{Writer: w, closers: []io.Closer{w, fh}}, nil
+}
+
+// readFile reads the given file, decompressing it if it has the extension of
+// a compressed file (see CompressionFromExtension). If max > 0, a file
+// (after decompression) larger than max bytes is an error, a *LimitError
+// for MaxEntrySize.
+func readFile(path string, max int) ([]byte, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	var r io.Reader = fh
+	if c := CompressionFromExtension(path); c != CompressionNone {
+		rc, err := NewReader(fh, c)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		r = rc
+	}
+	if max <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > max {
+		return nil, &LimitError{Limit: "MaxEntrySize", Max: max}
+	}
+	return data, nil
+}
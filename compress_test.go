@@ -0,0 +1,132 @@
+package ldif_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+// "dn: uid=someone,dc=example,dc=org\ncn: Someone\n" compressed with bzip2
+var bzip2Person = "QlpoOTFBWSZTWXHwL8gAAAnbgAAQQAQAEggALqfaQCAAMUDTQyMmIJVHqNMaNEeKKtDk0KYrKFmUSprYzzEEbboiXFxRH7/F3JFOFCQcfAvyAA=="
+
+func TestCompressedFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"export.ldif", "export.ldif.gz", "export.ldif.zst"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			w, err := ldif.CreateFile(path, nil)
+			if err != nil {
+				t.Fatalf("CreateFile: %s", err)
+			}
+			if _, err := io.WriteString(w, personLDIF); err != nil {
+				t.Fatalf("write: %s", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close: %s", err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read: %s", err)
+			}
+			if name != "export.ldif" && bytes.Contains(raw, []byte("dn:")) {
+				t.Errorf("file %s is not compressed", name)
+			}
+
+			r, err := ldif.OpenFile(path)
+			if err != nil {
+				t.Fatalf("OpenFile: %s", err)
+			}
+			defer r.Close()
+			l := &ldif.LDIF{}
+			if err := ldif.Unmarshal(r, l); err != nil {
+				t.Fatalf("Unmarshal: %s", err)
+			}
+			if len(l.Entries) != 1 || l.Entries[0].Entry.GetAttributeValue("uid") != "someone" {
+				t.Errorf("wrong entries: %#v", l.Entries)
+			}
+		})
+	}
+}
+
+func TestNewReaderDetection(t *testing.T) {
+	bz, _ := base64.StdEncoding.DecodeString(bzip2Person)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("dn: uid=someone,dc=example,dc=org\ncn: Someone\n"))
+	zw.Close()
+
+	for name, data := range map[string][]byte{
+		"plain": []byte("dn: uid=someone,dc=example,dc=org\ncn: Someone\n"),
+		"gzip":  gz.Bytes(),
+		"bzip2": bz,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := ldif.NewReader(bytes.NewReader(data), ldif.CompressionAuto)
+			if err != nil {
+				t.Fatalf("NewReader: %s", err)
+			}
+			l := &ldif.LDIF{}
+			if err := ldif.Unmarshal(r, l); err != nil {
+				t.Fatalf("Unmarshal: %s", err)
+			}
+			if l.Entries[0].Entry.GetAttributeValue("cn") != "Someone" {
+				t.Errorf("wrong entry: %#v", l.Entries[0].Entry)
+			}
+		})
+	}
+}
+
+func TestCreateFileBzip2(t *testing.T) {
+	if _, err := ldif.CreateFile(filepath.Join(t.TempDir(), "x.ldif.bz2"), nil); err == nil {
+		t.Error("expected error for writing bzip2")
+	}
+}
+
+func TestLDIFURLCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "description.txt.gz")
+	w, err := ldif.CreateFile(path, nil)
+	if err != nil {
+		t.Fatalf("CreateFile: %s", err)
+	}
+	io.WriteString(w, "TEST\n")
+	w.Close()
+
+	l, err := ldif.Parse("dn: uid=someone,dc=example,dc=org\ndescription:< file:///" + filepath.ToSlash(strings.TrimPrefix(path, "/")) + "\n")
+	if err != nil {
+		t.Fatalf("Failed to parse LDIF: %s", err)
+	}
+	if got := l.Entries[0].Entry.GetAttributeValue("description"); got != "TEST\n" {
+		t.Errorf("wrong value: %q", got)
+	}
+}
+
+// A compressed file behind a URL must not be decompressed beyond
+// Limits.MaxEntrySize.
+func TestLDIFURLCompressedLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bomb.txt.gz")
+	w, err := ldif.CreateFile(path, nil)
+	if err != nil {
+		t.Fatalf("CreateFile: %s", err)
+	}
+	w.Write(bytes.Repeat([]byte{0}, 1<<20))
+	w.Close()
+
+	in := "dn: uid=someone,dc=example,dc=org\ndescription:< file:///" + filepath.ToSlash(strings.TrimPrefix(path, "/")) + "\n"
+	err = ldif.Unmarshal(strings.NewReader(in), &ldif.LDIF{Limits: ldif.Limits{MaxEntrySize: 1024}})
+	var lerr *ldif.LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxEntrySize" {
+		t.Fatalf("expected MaxEntrySize LimitError, got %v", err)
+	}
+	if err := ldif.Unmarshal(strings.NewReader(in), &ldif.LDIF{Limits: ldif.Limits{MaxEntrySize: 2 << 20}}); err != nil {
+		t.Errorf("below the limit: %s", err)
+	}
+}
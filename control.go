@@ -0,0 +1,45 @@
+package ldif
+
+import (
+	"github.com/go-ldap/ldap/v3"
+)
+
+// controlParts splits an ldap.Control into its OID, criticality and the raw
+// control value (nil if the control has no value).
+func controlParts(c ldap.Control) (oid string, criticality bool, value []byte) {
+	switch ctrl := c.(type) {
+	case *ldap.ControlManageDsaIT:
+		return ctrl.GetControlType(), ctrl.Criticality, nil
+	case *ldap.ControlString:
+		if ctrl.ControlValue != "" {
+			value = []byte(ctrl.ControlValue)
+		}
+		return ctrl.ControlType, ctrl.Criticality, value
+	}
+
+	oid = c.GetControlType()
+	pkt := c.Encode()
+	if pkt == nil || len(pkt.Children) == 0 {
+		return oid, false, nil
+	}
+	for _, child := range pkt.Children[1:] {
+		if b, ok := child.Value.(bool); ok {
+			criticality = b
+			continue
+		}
+		if child.Data != nil {
+			value = child.Data.Bytes()
+		}
+	}
+	return oid, criticality, value
+}
+
+// newControl returns an ldap.Control for the given parts. Only the controls
+// the LDIF parser knows are returned with their specific type, all others are
+// returned as *ldap.ControlString.
+func newControl(oid string, criticality bool, value []byte) ldap.Control {
+	if oid == ldap.ControlTypeManageDsaIT && len(value) == 0 {
+		return &ldap.ControlManageDsaIT{Criticality: criticality}
+	}
+	return ldap.NewControlString(oid, criticality, string(value))
+}
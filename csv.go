@@ -0,0 +1,250 @@
+package ldif
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// CSVColumn maps one CSV column to an attribute.
+type CSVColumn struct {
+	// Name is the column header in the CSV
+	Name string
+	// Attribute is the attribute name, defaults to Name
+	Attribute string
+	// Binary columns hold base64 encoded values
+	Binary bool
+}
+
+// CSVMapping describes the conversion between CSV rows and content records.
+type CSVMapping struct {
+	// DN is the template for the DN of imported entries, a "{column}"
+	// is replaced by the (escaped) first value of that column, e.g.
+	// "uid={uid},ou=People,dc=example,dc=com".
+	DN string
+	// Columns lists the columns to import or export. When importing
+	// with an empty list, all columns are imported as attributes of
+	// the same name.
+	Columns []CSVColumn
+	// Separator splits a cell into multiple values. If empty, cells are
+	// not split on import and multi-valued attributes cannot be exported.
+	Separator string
+	// Comma is the field delimiter, defaults to ','.
+	Comma rune
+	// Constants are attributes added to every imported entry (e.g. the
+	// objectClass values), before the attributes from the columns.
+	Constants map[string][]string
+}
+
+func (m *CSVMapping) attribute(col CSVColumn) string {
+	if col.Attribute != "" {
+		return col.Attribute
+	}
+	return col.Name
+}
+
+// UnmarshalCSV reads CSV data with a header line from the given io.Reader
+// and yields one content record per row as described by the mapping.
+func UnmarshalCSV(r io.Reader, m *CSVMapping) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		if m == nil || m.DN == "" {
+			yield(nil, errors.New("CSV mapping requires a DN template"))
+			return
+		}
+		cr := csv.NewReader(r)
+		if m.Comma != 0 {
+			cr.Comma = m.Comma
+		}
+		header, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("missing CSV header")
+			}
+			yield(nil, err)
+			return
+		}
+
+		index := make(map[string]int, len(header))
+		for i, name := range header {
+			index[name] = i
+		}
+		columns := m.Columns
+		if len(columns) == 0 {
+			for _, name := range header {
+				columns = append(columns, CSVColumn{Name: name})
+			}
+		}
+		for _, col := range columns {
+			if _, ok := index[col.Name]; !ok {
+				yield(nil, fmt.Errorf("column %s not found in CSV header", col.Name))
+				return
+			}
+		}
+
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			line, _ := cr.FieldPos(0)
+			entry, err := m.entry(index, columns, record)
+			if err != nil {
+				yield(nil, fmt.Errorf("line %d: %s", line, err))
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *CSVMapping) entry(index map[string]int, columns []CSVColumn, record []string) (*Entry, error) {
+	values := make(map[string][]string, len(columns))
+	for _, col := range columns {
+		cell := record[index[col.Name]]
+		if cell == "" {
+			continue
+		}
+		vals := []string{cell}
+		if m.Separator != "" {
+			vals = strings.Split(cell, m.Separator)
+		}
+		if col.Binary {
+			for i, v := range vals {
+				dec, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid base64 value in column %s: %s", col.Name, err)
+				}
+				vals[i] = string(dec)
+			}
+		}
+		values[col.Name] = vals
+	}
+
+	dn, err := expandDNTemplate(m.DN, func(name string) (string, error) {
+		if _, ok := index[name]; !ok {
+			return "", fmt.Errorf("column %s of DN template not found in CSV header", name)
+		}
+		vals := values[name]
+		if len(vals) == 0 {
+			return "", fmt.Errorf("empty column %s used in DN template", name)
+		}
+		return escapeDNValue(vals[0]), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &ldap.Entry{DN: dn}
+	names := make([]string, 0, len(m.Constants))
+	for name := range m.Constants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(name, m.Constants[name]))
+	}
+	for _, col := range columns {
+		vals, ok := values[col.Name]
+		if !ok {
+			continue
+		}
+		entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(m.attribute(col), vals))
+	}
+	return &Entry{Entry: entry}, nil
+}
+
+func expandDNTemplate(tmpl string, value func(name string) (string, error)) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			return "", errors.New("unterminated placeholder in DN template")
+		}
+		val, err := value(tmpl[start+1 : start+end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(tmpl[:start])
+		b.WriteString(val)
+		tmpl = tmpl[start+end+1:]
+	}
+}
+
+// MarshalCSV writes the given entries as CSV to the io.Writer. The first
+// column is the DN (with the header "dn"), followed by the columns of the
+// mapping. Attribute names are matched case-insensitively, the DN template
+// is not used.
+func MarshalCSV(w io.Writer, entries []*ldap.Entry, m *CSVMapping) error {
+	if m == nil || len(m.Columns) == 0 {
+		return errors.New("CSV mapping requires columns for export")
+	}
+	cw := csv.NewWriter(w)
+	if m.Comma != 0 {
+		cw.Comma = m.Comma
+	}
+	header := []string{"dn"}
+	for _, col := range m.Columns {
+		header = append(header, col.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{e.DN}
+		for _, col := range m.Columns {
+			vals := attributeValues(e, m.attribute(col))
+			if len(vals) > 1 && m.Separator == "" {
+				return fmt.Errorf("%s of %s is multi-valued, but no separator is set", col.Name, e.DN)
+			}
+			if col.Binary {
+				enc := make([]string, len(vals))
+				for i, v := range vals {
+					enc[i] = base64.StdEncoding.EncodeToString([]byte(v))
+				}
+				vals = enc
+			} else if m.Separator != "" {
+				for _, v := range vals {
+					if strings.Contains(v, m.Separator) {
+						return fmt.Errorf("value of %s of %s contains the separator", col.Name, e.DN)
+					}
+				}
+			}
+			record = append(record, strings.Join(vals, m.Separator))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// attributeValues returns the values of all attributes of the entry with
+// the given name, compared case-insensitively.
+func attributeValues(e *ldap.Entry, name string) []string {
+	var vals []string
+	for _, attr := range e.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			vals = append(vals, attr.Values...)
+		}
+	}
+	return vals
+}
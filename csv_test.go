@@ -0,0 +1,132 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var hrCSV = `uid,givenName,sn,mail,photo
+jdoe,John,"Doe, Jr.",jdoe@example.com|john.doe@example.com,AAEC
+asmith,Anna,Smith,,
+`
+
+func TestUnmarshalCSV(t *testing.T) {
+	m := &ldif.CSVMapping{
+		DN: "uid={uid},ou=People,dc=example,dc=com",
+		Columns: []ldif.CSVColumn{
+			{Name: "uid"},
+			{Name: "sn"},
+			{Name: "mail"},
+			{Name: "photo", Attribute: "jpegPhoto", Binary: true},
+		},
+		Separator: "|",
+		Constants: map[string][]string{"objectClass": {"top", "inetOrgPerson"}},
+	}
+	l := &ldif.LDIF{}
+	for e, err := range ldif.UnmarshalCSV(strings.NewReader(hrCSV), m) {
+		if err != nil {
+			t.Fatalf("UnmarshalCSV: %s", err)
+		}
+		l.Entries = append(l.Entries, e)
+	}
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	expected := `dn: uid=jdoe,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: inetOrgPerson
+uid: jdoe
+sn: Doe, Jr.
+mail: jdoe@example.com
+mail: john.doe@example.com
+jpegPhoto:: AAEC
+
+dn: uid=asmith,ou=People,dc=example,dc=com
+objectClass: top
+objectClass: inetOrgPerson
+uid: asmith
+sn: Smith
+
+`
+	if out != expected {
+		t.Errorf("wrong LDIF:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestUnmarshalCSVDNEscaping(t *testing.T) {
+	m := &ldif.CSVMapping{DN: "cn={sn},dc=example,dc=com"}
+	for e, err := range ldif.UnmarshalCSV(strings.NewReader(hrCSV), m) {
+		if err != nil {
+			t.Fatalf("UnmarshalCSV: %s", err)
+		}
+		if e.Entry.DN != `cn=Doe\, Jr.,dc=example,dc=com` {
+			t.Errorf("wrong DN: %s", e.Entry.DN)
+		}
+		if len(e.Entry.Attributes) != 5 {
+			t.Errorf("expected all 5 columns as attributes, got %d", len(e.Entry.Attributes))
+		}
+		break
+	}
+}
+
+func TestUnmarshalCSVErrors(t *testing.T) {
+	for name, m := range map[string]*ldif.CSVMapping{
+		"no template":     {},
+		"unknown column":  {DN: "uid={uid},dc=example,dc=com", Columns: []ldif.CSVColumn{{Name: "foo"}}},
+		"unknown dn part": {DN: "uid={foo},dc=example,dc=com"},
+		"empty dn part":   {DN: "mail={mail},dc=example,dc=com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var failed bool
+			for _, err := range ldif.UnmarshalCSV(strings.NewReader(hrCSV), m) {
+				if err != nil {
+					failed = true
+				}
+			}
+			if !failed {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMarshalCSV(t *testing.T) {
+	people := []*ldap.Entry{
+		ldap.NewEntry("uid=jdoe,dc=example,dc=com", map[string][]string{
+			"uid":       {"jdoe"},
+			"Mail":      {"jdoe@example.com", "john.doe@example.com"},
+			"jpegPhoto": {"\x00\x01\x02"},
+		}),
+		ldap.NewEntry("uid=asmith,dc=example,dc=com", map[string][]string{
+			"uid": {"asmith"},
+		}),
+	}
+	m := &ldif.CSVMapping{
+		Columns: []ldif.CSVColumn{
+			{Name: "uid"},
+			{Name: "mail"},
+			{Name: "photo", Attribute: "jpegPhoto", Binary: true},
+		},
+		Separator: "|",
+	}
+	var buf strings.Builder
+	if err := ldif.MarshalCSV(&buf, people, m); err != nil {
+		t.Fatalf("MarshalCSV: %s", err)
+	}
+	expected := `dn,uid,mail,photo
+"uid=jdoe,dc=example,dc=com",jdoe,jdoe@example.com|john.doe@example.com,AAEC
+"uid=asmith,dc=example,dc=com",asmith,,
+`
+	if buf.String() != expected {
+		t.Errorf("wrong CSV:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	m.Separator = ""
+	if err := ldif.MarshalCSV(&strings.Builder{}, people, m); err == nil {
+		t.Error("expected error for multi-valued attribute without separator")
+	}
+}
@@ -0,0 +1,231 @@
+package ldif
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DiffOptions configure Diff.
+type DiffOptions struct {
+	// IgnoreOperational skips the attributes for which IsOperational
+	// returns true.
+	IgnoreOperational bool
+	// IgnoreAttributes are the names of further attributes which are not
+	// compared (case-insensitive).
+	IgnoreAttributes []string
+	// ValuesAsSets compares the values of an attribute as a set: the order
+	// of the values does not matter and changes are written as "add" and
+	// "delete" of single values. Otherwise a changed attribute is written
+	// as "replace" with all new values.
+	ValuesAsSets bool
+}
+
+func (o *DiffOptions) ignored(name string) bool {
+	if o.IgnoreOperational && IsOperational(name) {
+		return true
+	}
+	for _, n := range o.IgnoreAttributes {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffAttr is an attribute of a content record, the values of attributes
+// with the same name (case-insensitive) are merged.
+type diffAttr struct {
+	name   string
+	values []string
+}
+
+type diffEntry struct {
+	dn    string
+	depth int
+	keys  []string
+	attrs map[string]*diffAttr
+	seen  bool
+}
+
+func newDiffEntry(entry *Entry, opts *DiffOptions) (string, *diffEntry, error) {
+	var attrs []*ldap.EntryAttribute
+	switch {
+	case entry.Entry != nil:
+		attrs = entry.Entry.Attributes
+	case entry.Add != nil:
+		for _, a := range entry.Add.Attributes {
+			attrs = append(attrs, &ldap.EntryAttribute{Name: a.Type, Values: a.Vals})
+		}
+	default:
+		return "", nil, fmt.Errorf("%s is a change record, diff needs content records", entry.DN())
+	}
+	dn := entry.DN()
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, dn, err)
+	}
+	e := &diffEntry{dn: dn, depth: len(parsed.RDNs), attrs: make(map[string]*diffAttr)}
+	for _, a := range attrs {
+		if opts.ignored(a.Name) {
+			continue
+		}
+		key := strings.ToLower(a.Name)
+		if da, ok := e.attrs[key]; ok {
+			da.values = append(da.values, a.Values...)
+			continue
+		}
+		e.keys = append(e.keys, key)
+		e.attrs[key] = &diffAttr{name: a.Name, values: slices.Clone(a.Values)}
+	}
+	return normalizeParsedDN(parsed), e, nil
+}
+
+// Diff compares the content records of old and new, matched by their
+// normalized DN, and yields the change records which turn old into new:
+//
+//   - an add record for each entry which is only in new,
+//   - a modify record for each entry whose attributes differ,
+//   - a delete record for each entry which is only in old.
+//
+// The records can be written with Marshal, they are accepted by Unmarshal
+// and LDIF.Apply. Attribute names are compared case-insensitively, values
+// exactly. Add and modify records are yielded in the order of new, the
+// delete records follow at the end, children before their parents.
+//
+// The records of old are kept in memory, new is only streamed. Both may
+// contain content records (*ldap.Entry) and add records, any other record
+// or a DN which is in the same input twice is an error, which ends the
+// iteration.
+func Diff(old, new iter.Seq2[*Entry, error], opts *DiffOptions) iter.Seq2[*Entry, error] {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	return func(yield func(*Entry, error) bool) {
+		var order []*diffEntry
+		byDN := make(map[string]*diffEntry)
+		for entry, err := range old {
+			if err == nil {
+				var key string
+				var e *diffEntry
+				key, e, err = newDiffEntry(entry, opts)
+				if err == nil {
+					if _, ok := byDN[key]; ok {
+						err = fmt.Errorf("%w %s in old records", ErrDuplicateDN, e.dn)
+					}
+					byDN[key] = e
+					order = append(order, e)
+				}
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		seen := make(map[string]bool)
+		for entry, err := range new {
+			var change *Entry
+			if err == nil {
+				var key string
+				var e *diffEntry
+				key, e, err = newDiffEntry(entry, opts)
+				if err == nil {
+					if seen[key] {
+						err = fmt.Errorf("%w %s in new records", ErrDuplicateDN, e.dn)
+					}
+					seen[key] = true
+					if prev, ok := byDN[key]; ok {
+						prev.seen = true
+						change = diffModify(prev, e, opts)
+					} else {
+						change = diffAdd(e)
+					}
+				}
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if change != nil && !yield(change, nil) {
+				return
+			}
+		}
+
+		var deleted []*diffEntry
+		for _, e := range order {
+			if !e.seen {
+				deleted = append(deleted, e)
+			}
+		}
+		slices.SortStableFunc(deleted, func(a, b *diffEntry) int {
+			return b.depth - a.depth
+		})
+		for _, e := range deleted {
+			if !yield(&Entry{Del: ldap.NewDelRequest(e.dn, nil)}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func diffAdd(e *diffEntry) *Entry {
+	add := ldap.NewAddRequest(e.dn, nil)
+	for _, key := range e.keys {
+		add.Attribute(e.attrs[key].name, e.attrs[key].values)
+	}
+	return &Entry{Add: add}
+}
+
+// diffModify returns the modify record which changes the attributes of old
+// to the ones of new, nil if they are equal.
+func diffModify(old, new *diffEntry, opts *DiffOptions) *Entry {
+	mod := ldap.NewModifyRequest(new.dn, nil)
+	for _, key := range new.keys {
+		attr := new.attrs[key]
+		prev, ok := old.attrs[key]
+		switch {
+		case !ok:
+			mod.Add(attr.name, attr.values)
+		case opts.ValuesAsSets:
+			added := missingValues(attr.values, prev.values)
+			removed := missingValues(prev.values, attr.values)
+			if len(removed) > 0 {
+				mod.Delete(attr.name, removed)
+			}
+			if len(added) > 0 {
+				mod.Add(attr.name, added)
+			}
+		case !slices.Equal(attr.values, prev.values):
+			mod.Replace(attr.name, attr.values)
+		}
+	}
+	for _, key := range old.keys {
+		if _, ok := new.attrs[key]; !ok {
+			mod.Delete(old.attrs[key].name, nil)
+		}
+	}
+	if len(mod.Changes) == 0 {
+		return nil
+	}
+	return &Entry{Modify: mod}
+}
+
+// missingValues returns the distinct values of a which are not in b.
+func missingValues(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+	var missing []string
+	for _, v := range a {
+		if !in[v] {
+			missing = append(missing, v)
+			in[v] = true
+		}
+	}
+	return missing
+}
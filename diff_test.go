@@ -0,0 +1,198 @@
+package ldif_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var diffOld = `dn: dc=example,dc=org
+objectClass: domain
+dc: example
+
+dn: ou=People,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: jdoe
+cn: John Doe
+sn: Doe
+mail: jdoe@example.org
+mail: john@example.org
+telephoneNumber: 123
+modifyTimestamp: 20240101000000Z
+
+dn: ou=Groups,dc=example,dc=org
+objectClass: organizationalUnit
+ou: Groups
+
+dn: cn=admins,ou=Groups,dc=example,dc=org
+objectClass: groupOfNames
+cn: admins
+member: uid=jdoe,ou=People,dc=example,dc=org
+`
+
+var diffNew = `dn: DC=Example,DC=Org
+objectClass: domain
+dc: example
+
+dn: ou=people,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+description: all people
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: jdoe
+cn: John Doe
+SN: Doe
+mail: john@example.org
+mail: jdoe@example.org
+modifyTimestamp: 20250101000000Z
+
+dn: uid=asmith,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: asmith
+cn: Anna Smith
+sn: Smith
+`
+
+func diffString(t *testing.T, old, new string, opts *ldif.DiffOptions) string {
+	t.Helper()
+	l := &ldif.LDIF{}
+	for change, err := range ldif.Diff(
+		ldif.UnmarshalEntries(strings.NewReader(old), &ldif.LDIF{}),
+		ldif.UnmarshalEntries(strings.NewReader(new), &ldif.LDIF{}),
+		opts,
+	) {
+		if err != nil {
+			t.Fatalf("diff: %s", err)
+		}
+		l.Entries = append(l.Entries, change)
+	}
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	expected := `dn: ou=people,dc=example,dc=org
+changetype: modify
+add: description
+description: all people
+-
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modify
+replace: mail
+mail: john@example.org
+mail: jdoe@example.org
+-
+replace: modifyTimestamp
+modifyTimestamp: 20250101000000Z
+-
+delete: telephoneNumber
+-
+
+dn: uid=asmith,ou=People,dc=example,dc=org
+changetype: add
+cn: Anna Smith
+objectClass: inetOrgPerson
+sn: Smith
+uid: asmith
+
+dn: cn=admins,ou=Groups,dc=example,dc=org
+changetype: delete
+
+dn: ou=Groups,dc=example,dc=org
+changetype: delete
+
+`
+	out := diffString(t, diffOld, diffNew, nil)
+	if out != expected {
+		t.Fatalf("got:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	l, err := ldif.Parse(out)
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	conn := &recordingConn{}
+	if err := l.Apply(conn, false); err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+	if len(conn.adds) != 1 || len(conn.mods) != 2 || len(conn.dels) != 2 {
+		t.Errorf("applied %d adds, %d modifies, %d deletes", len(conn.adds), len(conn.mods), len(conn.dels))
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	expected := `dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modify
+delete: telephoneNumber
+-
+
+`
+	out := diffString(t, diffOld, diffNew, &ldif.DiffOptions{
+		IgnoreOperational: true,
+		IgnoreAttributes:  []string{"Description"},
+		ValuesAsSets:      true,
+	})
+	if !strings.HasPrefix(out, expected) {
+		t.Errorf("got:\n%s\nexpected prefix:\n%s", out, expected)
+	}
+
+	// attributes set by administrators are compared even if their schema
+	// says they are operational
+	old := "dn: cn=foo\ncn: foo\nmodifyTimestamp: 1\nnsAccountLock: false\n"
+	new := "dn: cn=foo\ncn: foo\nmodifyTimestamp: 2\nnsAccountLock: true\n"
+	expected = "dn: cn=foo\nchangetype: modify\nreplace: nsAccountLock\nnsAccountLock: true\n-\n\n"
+	if out := diffString(t, old, new, &ldif.DiffOptions{IgnoreOperational: true}); out != expected {
+		t.Errorf("nsAccountLock: got:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	old = "dn: cn=foo\ncn: foo\nmail: a\nmail: b\n"
+	new = "dn: cn=foo\ncn: foo\nmail: b\nmail: c\n"
+	expected = "dn: cn=foo\nchangetype: modify\ndelete: mail\nmail: a\n-\nadd: mail\nmail: c\n-\n\n"
+	if out := diffString(t, old, new, &ldif.DiffOptions{ValuesAsSets: true}); out != expected {
+		t.Errorf("sets: got:\n%s\nexpected:\n%s", out, expected)
+	}
+	if out := diffString(t, old, old, nil); out != "" {
+		t.Errorf("no differences: got:\n%s", out)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	diff := func(old, new string) error {
+		for _, err := range ldif.Diff(
+			ldif.UnmarshalEntries(strings.NewReader(old), &ldif.LDIF{}),
+			ldif.UnmarshalEntries(strings.NewReader(new), &ldif.LDIF{}),
+			nil,
+		) {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	dup := "dn: cn=foo\ncn: foo\n\ndn: CN=Foo\ncn: foo\n"
+	if err := diff(dup, ""); !errors.Is(err, ldif.ErrDuplicateDN) {
+		t.Errorf("duplicate in old: got %v", err)
+	}
+	if err := diff("", dup); !errors.Is(err, ldif.ErrDuplicateDN) {
+		t.Errorf("duplicate in new: got %v", err)
+	}
+	if err := diff("", "dn: cn=foo\nchangetype: delete\n"); err == nil {
+		t.Error("change record: expected an error")
+	}
+	if err := diff("dn: cn=foo\ncn foo\n", ""); !errors.Is(err, ldif.ErrInvalidLine) {
+		t.Errorf("parse error: got %v", err)
+	}
+}
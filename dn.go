@@ -0,0 +1,93 @@
+package ldif
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// escapeDNValue escapes an attribute value for use in a DN string as
+// described in RFC 4514, section 2.4.
+func escapeDNValue(val string) string {
+	var b strings.Builder
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';' || c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == 0:
+			b.WriteString("\\00")
+		case i == 0 && (c == ' ' || c == '#'):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case i == len(val)-1 && c == ' ':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// normalizeDN returns a normalized form of the DN, usable as a map key:
+// attribute types and values are lower cased, the attributes of a multi
+// valued RDN are sorted and all insignificant spaces and escapes are removed.
+//
+// Values are compared case-insensitively, which is correct for the usual
+// naming attributes (cn, uid, ou, dc, ...).
+func normalizeDN(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	return normalizeParsedDN(parsed), nil
+}
+
+func normalizeParsedDN(dn *ldap.DN) string {
+	rdns := make([]string, len(dn.RDNs))
+	for i, rdn := range dn.RDNs {
+		avas := make([]string, len(rdn.Attributes))
+		for j, ava := range rdn.Attributes {
+			avas[j] = strings.ToLower(ava.Type) + "=" + escapeDNValue(strings.ToLower(ava.Value))
+		}
+		sort.Strings(avas)
+		rdns[i] = strings.Join(avas, "+")
+	}
+	return strings.Join(rdns, ",")
+}
+
+// parentDN returns the normalized DN of the parent of the given DN, for a
+// DN with less than two RDNs, an empty string is returned.
+func parentDN(dn *ldap.DN) string {
+	if len(dn.RDNs) < 2 {
+		return ""
+	}
+	return normalizeParsedDN(&ldap.DN{RDNs: dn.RDNs[1:]})
+}
+
+// rdnKeys returns the normalized RDNs of the DN.
+func rdnKeys(dn *ldap.DN) []string {
+	keys := make([]string, len(dn.RDNs))
+	for i, rdn := range dn.RDNs {
+		keys[i] = normalizeParsedDN(&ldap.DN{RDNs: []*ldap.RelativeDN{rdn}})
+	}
+	return keys
+}
+
+// hasSuffix reports whether the DN ends with the normalized RDNs of the
+// suffix, i.e. is the suffix or an entry below it.
+func hasSuffix(dn *ldap.DN, suffix []string) bool {
+	n := len(dn.RDNs) - len(suffix)
+	if n < 0 {
+		return false
+	}
+	for i, rdn := range dn.RDNs[n:] {
+		if normalizeParsedDN(&ldap.DN{RDNs: []*ldap.RelativeDN{rdn}}) != suffix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,450 @@
+package ldif
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DSMLNamespace is the XML namespace of DSMLv2 documents.
+const DSMLNamespace = "urn:oasis:names:tc:DSML:2:0:core"
+
+const (
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+)
+
+type dsmlValue struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Value string     `xml:",chardata"`
+}
+
+type dsmlAttr struct {
+	Name   string      `xml:"name,attr"`
+	Values []dsmlValue `xml:"value"`
+}
+
+type dsmlModification struct {
+	Name      string      `xml:"name,attr"`
+	Operation string      `xml:"operation,attr"`
+	Values    []dsmlValue `xml:"value"`
+}
+
+type dsmlControl struct {
+	Type        string     `xml:"type,attr"`
+	Criticality string     `xml:"criticality,attr,omitempty"`
+	Value       *dsmlValue `xml:"controlValue"`
+}
+
+// dsmlRecord holds any of the DSML elements which can be converted to an
+// Entry: addRequest, delRequest, modifyRequest, modDNRequest and
+// searchResultEntry.
+type dsmlRecord struct {
+	XMLName       xml.Name
+	DN            string             `xml:"dn,attr"`
+	NewRDN        string             `xml:"newrdn,attr,omitempty"`
+	DeleteOldRDN  string             `xml:"deleteoldrdn,attr,omitempty"`
+	NewSuperior   string             `xml:"newSuperior,attr,omitempty"`
+	Controls      []dsmlControl      `xml:"control"`
+	Attrs         []dsmlAttr         `xml:"attr"`
+	Modifications []dsmlModification `xml:"modification"`
+}
+
+type dsmlErrorResponse struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message"`
+}
+
+type dsmlResultCode struct {
+	Code int `xml:"code,attr"`
+}
+
+type dsmlSearchResultDone struct {
+	XMLName    xml.Name       `xml:"searchResultDone"`
+	ResultCode dsmlResultCode `xml:"resultCode"`
+}
+
+// UnmarshalDSML parses a DSMLv2 document from the given io.Reader into the
+// LDIF struct. See UnmarshalDSMLEntries for the supported elements.
+func UnmarshalDSML(r io.Reader, l *LDIF) error {
+	for entry, err := range UnmarshalDSMLEntries(r, l) {
+		if err != nil {
+			return err
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+	return nil
+}
+
+// UnmarshalDSMLEntries parses a DSMLv2 document from the given io.Reader and
+// yields the entries during an iteration.
+//
+// The addRequest, delRequest, modifyRequest and modDNRequest elements of a
+// batchRequest are returned as change records, the searchResultEntry elements
+// of a searchResponse as content records. Any enclosing elements (like
+// batchRequest, batchResponse or a SOAP envelope) are ignored. Other
+// requests result in an error, other responses are skipped.
+//
+// Values of type xsd:anyURI are only read if l.DSMLURLs is set, otherwise
+// they are an error wrapping ErrURLDisabled. l may be nil.
+func UnmarshalDSMLEntries(r io.Reader, l *LDIF) iter.Seq2[*Entry, error] {
+	urls := l != nil && l.DSMLURLs
+	return func(yield func(*Entry, error) bool) {
+		if r == nil {
+			yield(nil, ErrNoReader)
+			return
+		}
+		dec := xml.NewDecoder(r)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			start, ok := tok.(xml.StartElement)
+			if !ok {
+				continue
+			}
+			switch start.Name.Local {
+			case "addRequest", "delRequest", "modifyRequest", "modDNRequest", "searchResultEntry":
+				var rec dsmlRecord
+				if err := dec.DecodeElement(&rec, &start); err != nil {
+					yield(nil, err)
+					return
+				}
+				entry, err := rec.toEntry(urls)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(entry, nil) {
+					return
+				}
+
+			case "searchRequest", "compareRequest", "extendedRequest", "abandonRequest", "authRequest":
+				yield(nil, fmt.Errorf("unsupported DSML request %s", start.Name.Local))
+				return
+
+			case "errorResponse":
+				var resp dsmlErrorResponse
+				if err := dec.DecodeElement(&resp, &start); err != nil {
+					yield(nil, err)
+					return
+				}
+				yield(nil, fmt.Errorf("DSML error response %s: %s", resp.Type, resp.Message))
+				return
+
+			case "searchResultDone", "searchResultReference", "addResponse", "delResponse",
+				"modifyResponse", "modDNResponse", "compareResponse", "extendedResponse", "authResponse":
+				if err := dec.Skip(); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (rec *dsmlRecord) toEntry(urls bool) (*Entry, error) {
+	var controls []ldap.Control
+	for _, c := range rec.Controls {
+		if c.Type == "" || validOID(c.Type) != nil {
+			return nil, fmt.Errorf("%s is not a valid oid", c.Type)
+		}
+		var value []byte
+		if c.Value != nil {
+			val, err := c.Value.decode(urls)
+			if err != nil {
+				return nil, err
+			}
+			value = []byte(val)
+		}
+		controls = append(controls, newControl(c.Type, c.Criticality == "true" || c.Criticality == "1", value))
+	}
+
+	switch rec.XMLName.Local {
+	case "searchResultEntry":
+		attrs, err := decodeDSMLAttrs(rec.Attrs, urls)
+		if err != nil {
+			return nil, err
+		}
+		entry := &ldap.Entry{DN: rec.DN}
+		for _, attr := range attrs {
+			entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(attr.Type, attr.Vals))
+		}
+		return &Entry{Entry: entry}, nil
+
+	case "addRequest":
+		attrs, err := decodeDSMLAttrs(rec.Attrs, urls)
+		if err != nil {
+			return nil, err
+		}
+		add := ldap.NewAddRequest(rec.DN, controls)
+		add.Attributes = attrs
+		return &Entry{Add: add}, nil
+
+	case "delRequest":
+		return &Entry{Del: ldap.NewDelRequest(rec.DN, controls)}, nil
+
+	case "modifyRequest":
+		mod := ldap.NewModifyRequest(rec.DN, controls)
+		for _, m := range rec.Modifications {
+			vals, err := decodeDSMLValues(m.Values, urls)
+			if err != nil {
+				return nil, err
+			}
+			switch m.Operation {
+			case "add":
+				mod.Add(m.Name, vals)
+			case "delete":
+				mod.Delete(m.Name, vals)
+			case "replace":
+				mod.Replace(m.Name, vals)
+			default:
+				return nil, fmt.Errorf("invalid operation %s in modify request", m.Operation)
+			}
+		}
+		return &Entry{Modify: mod}, nil
+
+	case "modDNRequest":
+		if len(controls) != 0 {
+			return nil, errors.New("controls are not supported for modDNRequest")
+		}
+		if rec.NewRDN == "" {
+			return nil, fmt.Errorf("modDNRequest for %s without newrdn", rec.DN)
+		}
+		// deleteoldrdn defaults to true in DSMLv2
+		deleteOldRDN := rec.DeleteOldRDN != "false" && rec.DeleteOldRDN != "0"
+		return &Entry{ModifyDN: ldap.NewModifyDNRequest(rec.DN, rec.NewRDN, deleteOldRDN, rec.NewSuperior)}, nil
+	}
+	return nil, fmt.Errorf("unsupported DSML element %s", rec.XMLName.Local)
+}
+
+func decodeDSMLAttrs(attrs []dsmlAttr, urls bool) ([]ldap.Attribute, error) {
+	var out []ldap.Attribute
+	for _, attr := range attrs {
+		vals, err := decodeDSMLValues(attr.Values, urls)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ldap.Attribute{Type: attr.Name, Vals: vals})
+	}
+	return out, nil
+}
+
+func decodeDSMLValues(values []dsmlValue, urls bool) ([]string, error) {
+	var vals []string
+	for _, v := range values {
+		val, err := v.decode(urls)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
+
+// decode returns the value, an xsd:anyURI value is read from its URL if
+// urls is true.
+func (v *dsmlValue) decode(urls bool) (string, error) {
+	var typ string
+	for _, attr := range v.Attrs {
+		if attr.Name.Local == "type" && (attr.Name.Space == xsiNamespace || attr.Name.Space == "xsi") {
+			typ = attr.Value
+		}
+	}
+	if i := strings.IndexByte(typ, ':'); i >= 0 {
+		typ = typ[i+1:]
+	}
+	switch typ {
+	case "", "string":
+		return v.Value, nil
+	case "base64Binary":
+		return decodeBase64(strings.Join(strings.Fields(v.Value), ""))
+	case "anyURI":
+		if !urls {
+			return "", fmt.Errorf("%w: cannot read %s", ErrURLDisabled, strings.TrimSpace(v.Value))
+		}
+		return readURLValue(strings.TrimSpace(v.Value), 0)
+	}
+	return "", fmt.Errorf("unsupported value type %s", typ)
+}
+
+// MarshalDSML writes the given LDIF as DSMLv2 document to the io.Writer.
+//
+// Content records are written as searchResultEntry elements of a
+// searchResponse in a batchResponse, change records as the matching
+// requests of a batchRequest. Values which are not valid UTF-8 or contain
+// characters not allowed in XML are written base64 encoded. As with
+// MarshalStreaming, change records and content records cannot be mixed.
+func MarshalDSML(l *LDIF, w io.Writer) error {
+	hasEntry := false
+	hasChange := false
+	for _, e := range l.Entries {
+		if e.Entry != nil {
+			hasEntry = true
+		} else {
+			hasChange = true
+		}
+	}
+	if hasEntry && hasChange {
+		return ErrMixed
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	root := xml.StartElement{
+		Name: xml.Name{Local: "batchRequest"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: DSMLNamespace},
+			{Name: xml.Name{Local: "xmlns:xsd"}, Value: xsdNamespace},
+			{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		},
+	}
+	if hasEntry {
+		root.Name.Local = "batchResponse"
+	}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	if hasEntry {
+		if err := enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "searchResponse"}}); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range l.Entries {
+		rec, err := newDSMLRecord(e)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	if hasEntry {
+		if err := enc.Encode(&dsmlSearchResultDone{}); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "searchResponse"}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newDSMLRecord(e *Entry) (*dsmlRecord, error) {
+	switch {
+	case e.Entry != nil:
+		rec := &dsmlRecord{XMLName: xml.Name{Local: "searchResultEntry"}, DN: e.Entry.DN}
+		for _, attr := range e.Entry.Attributes {
+			rec.Attrs = append(rec.Attrs, dsmlAttr{Name: attr.Name, Values: encodeDSMLValues(attr.Values)})
+		}
+		return rec, nil
+
+	case e.Add != nil:
+		rec := &dsmlRecord{XMLName: xml.Name{Local: "addRequest"}, DN: e.Add.DN, Controls: dsmlControls(e.Add.Controls)}
+		for _, attr := range e.Add.Attributes {
+			rec.Attrs = append(rec.Attrs, dsmlAttr{Name: attr.Type, Values: encodeDSMLValues(attr.Vals)})
+		}
+		return rec, nil
+
+	case e.Del != nil:
+		return &dsmlRecord{XMLName: xml.Name{Local: "delRequest"}, DN: e.Del.DN, Controls: dsmlControls(e.Del.Controls)}, nil
+
+	case e.Modify != nil:
+		rec := &dsmlRecord{XMLName: xml.Name{Local: "modifyRequest"}, DN: e.Modify.DN, Controls: dsmlControls(e.Modify.Controls)}
+		for _, ch := range e.Modify.Changes {
+			if ch.Operation > ldap.ReplaceAttribute {
+				return nil, fmt.Errorf("operation %d in modify request for %s is not supported by DSMLv2", ch.Operation, e.Modify.DN)
+			}
+			rec.Modifications = append(rec.Modifications, dsmlModification{
+				Name:      ch.Modification.Type,
+				Operation: modifyOps[ch.Operation],
+				Values:    encodeDSMLValues(ch.Modification.Vals),
+			})
+		}
+		return rec, nil
+
+	case e.ModifyDN != nil:
+		return &dsmlRecord{
+			XMLName:      xml.Name{Local: "modDNRequest"},
+			DN:           e.ModifyDN.DN,
+			NewRDN:       e.ModifyDN.NewRDN,
+			DeleteOldRDN: fmt.Sprintf("%t", e.ModifyDN.DeleteOldRDN),
+			NewSuperior:  e.ModifyDN.NewSuperior,
+		}, nil
+	}
+	return nil, errors.New("empty entry")
+}
+
+func encodeDSMLValues(values []string) []dsmlValue {
+	out := make([]dsmlValue, 0, len(values))
+	for _, v := range values {
+		if xmlSafe(v) {
+			out = append(out, dsmlValue{Value: v})
+			continue
+		}
+		out = append(out, dsmlValue{
+			Attrs: []xml.Attr{{Name: xml.Name{Local: "xsi:type"}, Value: "xsd:base64Binary"}},
+			Value: base64.StdEncoding.EncodeToString([]byte(v)),
+		})
+	}
+	return out
+}
+
+func dsmlControls(controls []ldap.Control) []dsmlControl {
+	var out []dsmlControl
+	for _, c := range controls {
+		oid, criticality, value := controlParts(c)
+		dc := dsmlControl{Type: oid}
+		if criticality {
+			dc.Criticality = "true"
+		}
+		if value != nil {
+			dc.Value = &dsmlValue{
+				Attrs: []xml.Attr{{Name: xml.Name{Local: "xsi:type"}, Value: "xsd:base64Binary"}},
+				Value: base64.StdEncoding.EncodeToString(value),
+			}
+		}
+		out = append(out, dc)
+	}
+	return out
+}
+
+// xmlSafe reports whether the value can be written as XML character data
+// and read back unchanged.
+func xmlSafe(v string) bool {
+	if !utf8.ValidString(v) {
+		return false
+	}
+	for _, r := range v {
+		switch {
+		case r == '\t' || r == '\n':
+		case r < ' ', r == 0xFFFE, r == 0xFFFF:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package ldif_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var dsmlBatchRequest = `<?xml version="1.0" encoding="UTF-8"?>
+<batchRequest xmlns="urn:oasis:names:tc:DSML:2:0:core"
+    xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <addRequest dn="uid=someone,dc=example,dc=org">
+    <attr name="objectClass"><value>top</value><value>person</value></attr>
+    <attr name="cn"><value>Someone</value></attr>
+    <attr name="jpegPhoto"><value xsi:type="xsd:base64Binary">/9j/AA==</value></attr>
+  </addRequest>
+  <delRequest dn="uid=gone,dc=example,dc=org">
+    <control type="2.16.840.1.113730.3.4.2" criticality="true"/>
+  </delRequest>
+  <modifyRequest dn="uid=someone,dc=example,dc=org">
+    <modification name="mail" operation="replace"><value>someone@example.org</value></modification>
+    <modification name="description" operation="delete"/>
+  </modifyRequest>
+  <modDNRequest dn="uid=someone,dc=example,dc=org" newrdn="uid=other" deleteoldrdn="false" newSuperior="ou=people,dc=example,dc=org"/>
+</batchRequest>
+`
+
+func TestUnmarshalDSMLBatchRequest(t *testing.T) {
+	l := &ldif.LDIF{}
+	if err := ldif.UnmarshalDSML(strings.NewReader(dsmlBatchRequest), l); err != nil {
+		t.Fatalf("UnmarshalDSML: %s", err)
+	}
+	if len(l.Entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(l.Entries))
+	}
+	add := l.Entries[0].Add
+	if add == nil || len(add.Attributes) != 3 {
+		t.Fatalf("wrong add request: %#v", l.Entries[0])
+	}
+	if got := add.Attributes[2].Vals[0]; got != "\xff\xd8\xff\x00" {
+		t.Errorf("wrong base64 value: %q", got)
+	}
+	if ctrl, ok := l.Entries[1].Del.Controls[0].(*ldap.ControlManageDsaIT); !ok || !ctrl.Criticality {
+		t.Errorf("wrong control: %#v", l.Entries[1].Del.Controls)
+	}
+	mod := l.Entries[2].Modify
+	if len(mod.Changes) != 2 || mod.Changes[1].Operation != ldap.DeleteAttribute {
+		t.Errorf("wrong modify request: %#v", mod)
+	}
+	mdn := l.Entries[3].ModifyDN
+	if mdn == nil || mdn.NewRDN != "uid=other" || mdn.DeleteOldRDN || mdn.NewSuperior != "ou=people,dc=example,dc=org" {
+		t.Errorf("wrong moddn request: %#v", mdn)
+	}
+
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	if !strings.Contains(out, "changetype: modrdn\nnewrdn: uid=other\ndeleteoldrdn: 0\nnewsuperior: ou=people,dc=example,dc=org\n") {
+		t.Errorf("wrong LDIF for moddn request:\n%s", out)
+	}
+}
+
+var dsmlSearchResponse = `<batchResponse xmlns="urn:oasis:names:tc:DSML:2:0:core">
+  <searchResponse>
+    <searchResultEntry dn="uid=someone,dc=example,dc=org">
+      <attr name="cn"><value>Someone</value></attr>
+      <attr name="sn"><value>One</value></attr>
+    </searchResultEntry>
+    <searchResultEntry dn="uid=other,dc=example,dc=org">
+      <attr name="cn"><value>Other</value></attr>
+    </searchResultEntry>
+    <searchResultDone><resultCode code="0"/></searchResultDone>
+  </searchResponse>
+</batchResponse>`
+
+func TestUnmarshalDSMLSearchResponse(t *testing.T) {
+	var dns []string
+	for e, err := range ldif.UnmarshalDSMLEntries(strings.NewReader(dsmlSearchResponse), nil) {
+		if err != nil {
+			t.Fatalf("UnmarshalDSMLEntries: %s", err)
+		}
+		if e.Entry == nil {
+			t.Fatalf("expected content record, got %#v", e)
+		}
+		dns = append(dns, e.Entry.DN)
+	}
+	if len(dns) != 2 || dns[1] != "uid=other,dc=example,dc=org" {
+		t.Errorf("wrong entries: %#v", dns)
+	}
+}
+
+func TestUnmarshalDSMLErrors(t *testing.T) {
+	for name, in := range map[string]string{
+		"search request": `<batchRequest><searchRequest dn="dc=example,dc=org"/></batchRequest>`,
+		"error response": `<batchResponse><errorResponse type="other"><message>boom</message></errorResponse></batchResponse>`,
+		"bad operation":  `<batchRequest><modifyRequest dn="cn=x"><modification name="cn" operation="foo"/></modifyRequest></batchRequest>`,
+		"broken xml":     `<batchRequest><delRequest dn="cn=x">`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := ldif.UnmarshalDSML(strings.NewReader(in), &ldif.LDIF{}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMarshalDSMLRoundTrip(t *testing.T) {
+	for name, in := range map[string]string{
+		"changes": dsmlBatchRequest,
+		"content": dsmlSearchResponse,
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := &ldif.LDIF{}
+			if err := ldif.UnmarshalDSML(strings.NewReader(in), l); err != nil {
+				t.Fatalf("UnmarshalDSML: %s", err)
+			}
+			var buf strings.Builder
+			if err := ldif.MarshalDSML(l, &buf); err != nil {
+				t.Fatalf("MarshalDSML: %s", err)
+			}
+			l2 := &ldif.LDIF{}
+			if err := ldif.UnmarshalDSML(strings.NewReader(buf.String()), l2); err != nil {
+				t.Fatalf("UnmarshalDSML of output: %s\n%s", err, buf.String())
+			}
+			first, _ := ldif.Marshal(l)
+			second, _ := ldif.Marshal(l2)
+			if first != second {
+				t.Errorf("round trip differs:\n%s\n%s", first, second)
+			}
+		})
+	}
+}
+
+func TestMarshalDSMLMixed(t *testing.T) {
+	l, err := ldif.ToLDIF(entries[0], ldap.NewDelRequest("cn=x", nil))
+	if err != nil {
+		t.Fatalf("ToLDIF: %s", err)
+	}
+	if err := ldif.MarshalDSML(l, &strings.Builder{}); err != ldif.ErrMixed {
+		t.Errorf("expected ErrMixed, got %v", err)
+	}
+}
+
+func TestUnmarshalDSMLURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	in := `<batchRequest xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><addRequest dn="cn=x,dc=example,dc=org">` +
+		`<attr name="jpegPhoto"><value xsi:type="xsd:anyURI">file://` + path + `</value></attr></addRequest></batchRequest>`
+
+	err := ldif.UnmarshalDSML(strings.NewReader(in), &ldif.LDIF{})
+	if !errors.Is(err, ldif.ErrURLDisabled) {
+		t.Errorf("default: expected ErrURLDisabled, got %v", err)
+	}
+	for _, err := range ldif.UnmarshalDSMLEntries(strings.NewReader(in), nil) {
+		if !errors.Is(err, ldif.ErrURLDisabled) {
+			t.Errorf("nil LDIF: expected ErrURLDisabled, got %v", err)
+		}
+	}
+
+	l := &ldif.LDIF{DSMLURLs: true}
+	if err := ldif.UnmarshalDSML(strings.NewReader(in), l); err != nil {
+		t.Fatalf("DSMLURLs: %s", err)
+	}
+	if v := l.Entries[0].Add.Attributes[0].Vals[0]; v != "secret" {
+		t.Errorf("DSMLURLs: got %q", v)
+	}
+}
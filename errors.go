@@ -0,0 +1,111 @@
+package ldif
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The kinds of parse errors. A *ParseError wraps one of these (if the kind
+// is known) and the underlying cause, so they can be tested with errors.Is:
+//
+//	if errors.Is(err, ldif.ErrInvalidBase64) {
+//		...
+//	}
+//
+// ErrMixed is returned when change records and content records are mixed.
+var (
+	ErrNoReader               = errors.New("no reader present")
+	ErrEmptyRecord            = errors.New("empty record")
+	ErrMissingDN              = errors.New("missing dn")
+	ErrInvalidDN              = errors.New("invalid dn")
+	ErrDuplicateDN            = errors.New("duplicate dn")
+	ErrInvalidVersion         = errors.New("invalid version")
+	ErrInvalidLine            = errors.New("invalid line")
+	ErrInvalidAttributeName   = errors.New("invalid attribute name")
+	ErrInvalidValue           = errors.New("invalid value")
+	ErrInvalidBase64          = errors.New("invalid base64 value")
+	ErrInvalidURL             = errors.New("invalid URL")
+	ErrUnsupportedURLScheme   = errors.New("unsupported URL scheme")
+	ErrURLDisabled            = errors.New("URL values are disabled")
+	ErrInvalidControl         = errors.New("invalid control")
+	ErrUnsupportedControl     = errors.New("unsupported control")
+	ErrInvalidChangeType      = errors.New("invalid changetype")
+	ErrInvalidChangeRecord    = errors.New("invalid change record")
+	ErrInvalidModifyOperation = errors.New("invalid modify operation")
+	ErrUnterminatedModify     = errors.New("unterminated modify operation")
+)
+
+// recordError is the error returned while parsing a record. Its message is
+// the message of the ParseError, it wraps the kind and the cause, if any.
+type recordError struct {
+	kind  error
+	cause error
+	msg   string
+	attr  string
+	dn    string
+}
+
+func (e *recordError) Error() string {
+	return e.msg
+}
+
+func (e *recordError) Unwrap() []error {
+	var errs []error
+	if e.kind != nil {
+		errs = append(errs, e.kind)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// errorf returns a *recordError of the given kind with a formatted message.
+func errorf(kind error, format string, args ...any) error {
+	return &recordError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// wrapError returns a *recordError of the given kind with the cause and its
+// message.
+func wrapError(kind, cause error) error {
+	return &recordError{kind: kind, cause: cause, msg: cause.Error()}
+}
+
+// withAttr sets the attribute name of err, if it is not set yet. Errors
+// which are not a *recordError are wrapped.
+func withAttr(err error, attr string) error {
+	re := asRecordError(err)
+	if re.attr == "" {
+		re.attr = attr
+	}
+	return re
+}
+
+// withDN sets the DN of err, if it is not set yet. Errors which are not a
+// *recordError are wrapped.
+func withDN(err error, dn string) error {
+	re := asRecordError(err)
+	if re.dn == "" {
+		re.dn = dn
+	}
+	return re
+}
+
+func asRecordError(err error) *recordError {
+	if re, ok := err.(*recordError); ok {
+		return re
+	}
+	return &recordError{cause: err, msg: err.Error()}
+}
+
+// newParseError returns a *ParseError for the error in the given line, with
+// the attribute name and DN of a *recordError.
+func newParseError(line int, err error) *ParseError {
+	perr := &ParseError{Line: line, Message: err.Error(), Err: err}
+	var re *recordError
+	if errors.As(err, &re) {
+		perr.Attribute = re.attr
+		perr.DN = re.dn
+	}
+	return perr
+}
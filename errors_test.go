@@ -0,0 +1,156 @@
+package ldif_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+func TestParseErrorKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		ldif  string
+		kind  error
+		attr  string
+		dn    string
+		ctrls bool
+	}{
+		{
+			name: "missing dn",
+			ldif: "cn: foo\n",
+			kind: ldif.ErrMissingDN,
+		},
+		{
+			name: "invalid version",
+			ldif: "version: 2\ndn: cn=foo\ncn: foo\n",
+			kind: ldif.ErrInvalidVersion,
+		},
+		{
+			name: "version not a number",
+			ldif: "version: one\ndn: cn=foo\ncn: foo\n",
+			kind: ldif.ErrInvalidVersion,
+		},
+		{
+			name: "missing colon",
+			ldif: "dn: cn=foo\ncn foo\n",
+			kind: ldif.ErrInvalidLine,
+			dn:   "cn=foo",
+		},
+		{
+			name: "invalid attribute name",
+			ldif: "dn: cn=foo\nc_n: foo\n",
+			kind: ldif.ErrInvalidAttributeName,
+			attr: "c_n",
+			dn:   "cn=foo",
+		},
+		{
+			name: "bad base64",
+			ldif: "dn: cn=foo\ndescription:: !!!\n",
+			kind: ldif.ErrInvalidBase64,
+			attr: "description",
+			dn:   "cn=foo",
+		},
+		{
+			name: "unsupported URL scheme",
+			ldif: "dn: cn=foo\njpegPhoto:< http://example.org/foo.jpg\n",
+			kind: ldif.ErrUnsupportedURLScheme,
+			attr: "jpegPhoto",
+			dn:   "cn=foo",
+		},
+		{
+			name:  "unsupported control",
+			ldif:  "dn: cn=foo\ncontrol: 1.2.3.4 true\nchangetype: delete\n",
+			kind:  ldif.ErrUnsupportedControl,
+			dn:    "cn=foo",
+			ctrls: true,
+		},
+		{
+			name:  "invalid control oid",
+			ldif:  "dn: cn=foo\ncontrol: 1..2 true\nchangetype: delete\n",
+			kind:  ldif.ErrInvalidControl,
+			dn:    "cn=foo",
+			ctrls: true,
+		},
+		{
+			name: "invalid changetype",
+			ldif: "dn: cn=foo\nchangetype: rename\n",
+			kind: ldif.ErrInvalidChangeType,
+			attr: "changetype",
+			dn:   "cn=foo",
+		},
+		{
+			name: "attributes for delete",
+			ldif: "dn: cn=foo\nchangetype: delete\ncn: foo\nsn: bar\n",
+			kind: ldif.ErrInvalidChangeRecord,
+			dn:   "cn=foo",
+		},
+		{
+			name: "unterminated modify",
+			ldif: "dn: cn=foo\nchangetype: modify\nadd: mail\nmail: foo@example.org\n",
+			kind: ldif.ErrUnterminatedModify,
+			dn:   "cn=foo",
+		},
+		{
+			name: "invalid modify operation",
+			ldif: "dn: cn=foo\nchangetype: modify\nadd: mail\ncn: foo\n-\n",
+			kind: ldif.ErrInvalidModifyOperation,
+			attr: "cn",
+			dn:   "cn=foo",
+		},
+		{
+			name: "increment with two values",
+			ldif: "dn: cn=foo\nchangetype: modify\nincrement: uidNumber\nuidNumber: 1\nuidNumber: 2\n-\n",
+			kind: ldif.ErrInvalidModifyOperation,
+			attr: "uidNumber",
+			dn:   "cn=foo",
+		},
+		{
+			name: "only a dn",
+			ldif: "dn: cn=foo\n",
+			kind: ldif.ErrEmptyRecord,
+			dn:   "cn=foo",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.ctrls {
+				_, err = ldif.ParseWithControls(tc.ldif)
+			} else {
+				_, err = ldif.Parse(tc.ldif)
+			}
+			if !errors.Is(err, tc.kind) {
+				t.Fatalf("expected %v, got %v", tc.kind, err)
+			}
+			var perr *ldif.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected ParseError, got %v", err)
+			}
+			if perr.Attribute != tc.attr {
+				t.Errorf("got attribute %q, expected %q", perr.Attribute, tc.attr)
+			}
+			if perr.DN != tc.dn {
+				t.Errorf("got DN %q, expected %q", perr.DN, tc.dn)
+			}
+		})
+	}
+}
+
+func TestParseErrorCause(t *testing.T) {
+	_, err := ldif.Parse("dn: cn=foo\ndescription:: Zm9v!\n")
+	var cerr base64.CorruptInputError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected base64.CorruptInputError, got %#v", err)
+	}
+	if err.Error() != "Error in line 3: illegal base64 data at input byte 4" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	_, err = ldif.Parse("dn: cn=foo\njpegPhoto:< file:///does/not/exist\n")
+	var perr *ldif.ParseError
+	if !errors.As(err, &perr) || perr.Attribute != "jpegPhoto" {
+		t.Fatalf("expected ParseError for jpegPhoto, got %v", err)
+	}
+}
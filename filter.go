@@ -0,0 +1,371 @@
+package ldif
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// A Filter is an LDAP search filter (RFC 4515) which can be matched against
+// entries without a server.
+//
+// Attribute names are compared case-insensitively. Values are compared
+// like with the caseIgnoreMatch rules: case-insensitive and with leading,
+// trailing and repeated spaces ignored. For the ordering matches (<= and
+// >=), values which are both integers are compared as numbers, others as
+// strings, which is correct for GeneralizedTime values. The approximate
+// match (~=) also ignores all spaces and punctuation.
+//
+// An extensible match may only use the caseIgnoreMatch (2.5.13.2) or
+// caseExactMatch (2.5.13.5) rule, with ":dn" the RDN values of the entry's
+// DN are matched, too.
+type Filter struct {
+	root *filterNode
+}
+
+type filterNode struct {
+	op       ber.Tag
+	children []*filterNode
+	attr     string
+	value    string
+	// substrings
+	initial string
+	any     []string
+	final   string
+	// extensible match
+	exact   bool
+	dnAttrs bool
+}
+
+// NewFilter compiles the filter with the filter compiler of go-ldap.
+func NewFilter(filter string) (*Filter, error) {
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	root, err := compileFilterNode(packet)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{root: root}, nil
+}
+
+func packetString(p *ber.Packet) string {
+	return ber.DecodeString(p.Data.Bytes())
+}
+
+func compileFilterNode(p *ber.Packet) (*filterNode, error) {
+	n := &filterNode{op: p.Tag}
+	switch p.Tag {
+	case ldap.FilterAnd, ldap.FilterOr, ldap.FilterNot:
+		for _, c := range p.Children {
+			child, err := compileFilterNode(c)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		}
+	case ldap.FilterEqualityMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual, ldap.FilterApproxMatch:
+		n.attr = packetString(p.Children[0])
+		n.value = packetString(p.Children[1])
+	case ldap.FilterPresent:
+		n.attr = packetString(p)
+	case ldap.FilterSubstrings:
+		n.attr = packetString(p.Children[0])
+		for _, c := range p.Children[1].Children {
+			switch c.Tag {
+			case ldap.FilterSubstringsInitial:
+				n.initial = packetString(c)
+			case ldap.FilterSubstringsAny:
+				n.any = append(n.any, packetString(c))
+			case ldap.FilterSubstringsFinal:
+				n.final = packetString(c)
+			}
+		}
+	case ldap.FilterExtensibleMatch:
+		for _, c := range p.Children {
+			switch c.Tag {
+			case ldap.MatchingRuleAssertionMatchingRule:
+				switch rule := packetString(c); strings.ToLower(rule) {
+				case "caseignorematch", "2.5.13.2":
+				case "caseexactmatch", "2.5.13.5":
+					n.exact = true
+				default:
+					return nil, fmt.Errorf("unsupported matching rule %q", rule)
+				}
+			case ldap.MatchingRuleAssertionType:
+				n.attr = packetString(c)
+			case ldap.MatchingRuleAssertionMatchValue:
+				n.value = packetString(c)
+			case ldap.MatchingRuleAssertionDNAttributes:
+				n.dnAttrs, _ = c.Value.(bool)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported filter type %d", p.Tag)
+	}
+	return n, nil
+}
+
+// Match reports whether the entry matches the filter.
+func (f *Filter) Match(entry *ldap.Entry) bool {
+	return f.root.match(entry)
+}
+
+func (n *filterNode) match(entry *ldap.Entry) bool {
+	switch n.op {
+	case ldap.FilterAnd:
+		for _, c := range n.children {
+			if !c.match(entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range n.children {
+			if c.match(entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !n.children[0].match(entry)
+	case ldap.FilterPresent:
+		return len(attributeValues(entry, n.attr)) > 0
+	case ldap.FilterExtensibleMatch:
+		return n.matchExtensible(entry)
+	}
+	for _, v := range attributeValues(entry, n.attr) {
+		if n.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *filterNode) matchValue(v string) bool {
+	switch n.op {
+	case ldap.FilterEqualityMatch:
+		return normalizeValue(v) == normalizeValue(n.value)
+	case ldap.FilterApproxMatch:
+		return approxValue(v) == approxValue(n.value)
+	case ldap.FilterGreaterOrEqual:
+		return compareValues(v, n.value) >= 0
+	case ldap.FilterLessOrEqual:
+		return compareValues(v, n.value) <= 0
+	case ldap.FilterSubstrings:
+		v = normalizeValue(v)
+		initial := normalizeValue(n.initial)
+		if !strings.HasPrefix(v, initial) {
+			return false
+		}
+		v = v[len(initial):]
+		for _, a := range n.any {
+			i := strings.Index(v, normalizeValue(a))
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(normalizeValue(a)):]
+		}
+		return strings.HasSuffix(v, normalizeValue(n.final))
+	}
+	return false
+}
+
+func (n *filterNode) matchExtensible(entry *ldap.Entry) bool {
+	equal := func(v string) bool {
+		if n.exact {
+			return v == n.value
+		}
+		return normalizeValue(v) == normalizeValue(n.value)
+	}
+	if n.attr == "" {
+		for _, a := range entry.Attributes {
+			for _, v := range a.Values {
+				if equal(v) {
+					return true
+				}
+			}
+		}
+	}
+	for _, v := range attributeValues(entry, n.attr) {
+		if equal(v) {
+			return true
+		}
+	}
+	if n.dnAttrs {
+		dn, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			return false
+		}
+		for _, rdn := range dn.RDNs {
+			for _, ava := range rdn.Attributes {
+				if (n.attr == "" || strings.EqualFold(ava.Type, n.attr)) && equal(ava.Value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// normalizeValue lower cases the value and removes leading, trailing and
+// repeated spaces.
+func normalizeValue(v string) string {
+	return strings.ToLower(strings.Join(strings.Fields(v), " "))
+}
+
+// approxValue returns the lower cased letters and digits of the value.
+func approxValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, v)
+}
+
+func compareValues(a, b string) int {
+	ai, aerr := strconv.ParseInt(strings.TrimSpace(a), 10, 64)
+	bi, berr := strconv.ParseInt(strings.TrimSpace(b), 10, 64)
+	if aerr == nil && berr == nil {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(normalizeValue(a), normalizeValue(b))
+}
+
+// Search reads the LDIF in r like UnmarshalEntries and yields the entries
+// which match the search request, like an LDAP server would return them:
+//
+//   - only entries within BaseDN and Scope (ldap.ScopeBaseObject,
+//     ScopeSingleLevel or ScopeWholeSubtree) are returned, an empty BaseDN
+//     is the root of all entries,
+//   - the entries must match the Filter, an empty filter matches all,
+//   - the entries contain only the requested Attributes: "*" selects all
+//     but the operational attributes (see IsOperational), "+" only those,
+//     "1.1" none. Without Attributes, all attributes are returned,
+//   - with TypesOnly, the attributes have no values,
+//   - at most SizeLimit entries are returned, if it is > 0.
+//
+// Add records are searched like content records, other change records are
+// skipped. Other fields of the request are ignored.
+func Search(r io.Reader, l *LDIF, req *ldap.SearchRequest) iter.Seq2[*ldap.Entry, error] {
+	return func(yield func(*ldap.Entry, error) bool) {
+		filter := &Filter{root: &filterNode{op: ldap.FilterAnd}}
+		if req.Filter != "" {
+			var err error
+			if filter, err = NewFilter(req.Filter); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+		base, err := ldap.ParseDN(req.BaseDN)
+		if err != nil {
+			yield(nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, req.BaseDN, err))
+			return
+		}
+		baseDN := normalizeParsedDN(base)
+
+		found := 0
+		for record, err := range UnmarshalEntries(r, l) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			entry := contentEntry(record)
+			if entry == nil {
+				continue
+			}
+			dn, err := ldap.ParseDN(entry.DN)
+			if err != nil {
+				yield(nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN, err))
+				return
+			}
+			if !inScope(dn, base, baseDN, req.Scope) || !filter.Match(entry) {
+				continue
+			}
+			if !yield(selectAttributes(entry, req.Attributes, req.TypesOnly), nil) {
+				return
+			}
+			found++
+			if req.SizeLimit > 0 && found >= req.SizeLimit {
+				return
+			}
+		}
+	}
+}
+
+// contentEntry returns the entry of a content or add record, nil for other
+// records.
+func contentEntry(record *Entry) *ldap.Entry {
+	switch {
+	case record.Entry != nil:
+		return record.Entry
+	case record.Add != nil:
+		entry := &ldap.Entry{DN: record.Add.DN}
+		for _, a := range record.Add.Attributes {
+			entry.Attributes = append(entry.Attributes, &ldap.EntryAttribute{Name: a.Type, Values: a.Vals})
+		}
+		return entry
+	}
+	return nil
+}
+
+func inScope(dn, base *ldap.DN, baseDN string, scope int) bool {
+	depth := len(dn.RDNs) - len(base.RDNs)
+	switch {
+	case depth < 0:
+		return false
+	case scope == ldap.ScopeBaseObject && depth != 0:
+		return false
+	case scope == ldap.ScopeSingleLevel && depth != 1:
+		return false
+	}
+	return normalizeParsedDN(&ldap.DN{RDNs: dn.RDNs[depth:]}) == baseDN
+}
+
+func selectAttributes(entry *ldap.Entry, names []string, typesOnly bool) *ldap.Entry {
+	if len(names) == 0 && !typesOnly {
+		return entry
+	}
+	all := len(names) == 0
+	var user, operational bool
+	for _, name := range names {
+		switch name {
+		case "*":
+			user = true
+		case "+":
+			operational = true
+		}
+	}
+	result := &ldap.Entry{DN: entry.DN}
+	for _, a := range entry.Attributes {
+		selected := all || (IsOperational(a.Name) && operational) || (!IsOperational(a.Name) && user)
+		for _, name := range names {
+			if strings.EqualFold(name, a.Name) {
+				selected = true
+			}
+		}
+		if !selected {
+			continue
+		}
+		attr := &ldap.EntryAttribute{Name: a.Name, Values: a.Values, ByteValues: a.ByteValues}
+		if typesOnly {
+			attr.Values, attr.ByteValues = nil, nil
+		}
+		result.Attributes = append(result.Attributes, attr)
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var searchLDIF = `dn: dc=example,dc=org
+objectClass: domain
+dc: example
+
+dn: ou=People,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: posixAccount
+objectClass: inetOrgPerson
+uid: jdoe
+cn: John  Doe
+sn: Doe
+uidNumber: 1000
+loginShell: /bin/bash
+mail: jdoe@example.org
+createTimestamp: 20240101000000Z
+
+dn: uid=asmith,ou=People,dc=example,dc=org
+objectClass: posixAccount
+objectClass: inetOrgPerson
+uid: asmith
+cn: Anna Smith
+sn: Smith
+uidNumber: 999
+loginShell: /bin/zsh
+createTimestamp: 20250101000000Z
+
+dn: cn=admins,ou=Groups,dc=example,dc=org
+changetype: add
+objectClass: groupOfNames
+cn: admins
+member: uid=jdoe,ou=People,dc=example,dc=org
+`
+
+func TestFilterMatch(t *testing.T) {
+	entry := ldap.NewEntry("uid=jdoe,ou=People,dc=example,dc=org", map[string][]string{
+		"objectClass":     {"top", "posixAccount"},
+		"uid":             {"jdoe"},
+		"cn":              {"John  Doe"},
+		"uidNumber":       {"1000"},
+		"loginShell":      {"/bin/bash"},
+		"modifyTimestamp": {"20240101000000Z"},
+	})
+	for filter, expected := range map[string]bool{
+		"(objectClass=posixAccount)":                             true,
+		"(OBJECTCLASS=POSIXACCOUNT)":                             true,
+		"(&(objectClass=posixAccount)(!(loginShell=/bin/bash)))": false,
+		"(|(uid=nobody)(uid=jdoe))":                              true,
+		"(cn=john doe)":                                          true,
+		"(mail=*)":                                               false,
+		"(uid=*)":                                                true,
+		"(cn=Jo*Doe)":                                            true,
+		"(cn=*ohn*o*)":                                           true,
+		"(cn=*smith)":                                            false,
+		"(uidNumber>=999)":                                       true,
+		"(uidNumber<=999)":                                       false,
+		"(modifyTimestamp>=20231231000000Z)":                     true,
+		"(cn~=johndoe)":                                          true,
+		"(uid:caseExactMatch:=JDOE)":                             false,
+		"(uid:caseIgnoreMatch:=JDOE)":                            true,
+		"(ou:dn:=people)":                                        true,
+		"(ou:=people)":                                           false,
+		"(:dn:=example)":                                         true,
+	} {
+		f, err := ldif.NewFilter(filter)
+		if err != nil {
+			t.Errorf("%s: %s", filter, err)
+			continue
+		}
+		if got := f.Match(entry); got != expected {
+			t.Errorf("%s: got %v, expected %v", filter, got, expected)
+		}
+	}
+
+	for _, filter := range []string{"uid=jdoe", "(uid=jdoe", "(uid:1.2.3:=jdoe)"} {
+		if _, err := ldif.NewFilter(filter); err == nil {
+			t.Errorf("%s: expected an error", filter)
+		}
+	}
+}
+
+func search(t *testing.T, req *ldap.SearchRequest) []*ldap.Entry {
+	t.Helper()
+	var entries []*ldap.Entry
+	for entry, err := range ldif.Search(strings.NewReader(searchLDIF), &ldif.LDIF{}, req) {
+		if err != nil {
+			t.Fatalf("search: %s", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func dns(entries []*ldap.Entry) string {
+	var dns []string
+	for _, e := range entries {
+		dns = append(dns, e.DN)
+	}
+	return strings.Join(dns, ";")
+}
+
+func TestSearch(t *testing.T) {
+	for _, tc := range []struct {
+		req      *ldap.SearchRequest
+		expected string
+	}{
+		{&ldap.SearchRequest{Scope: ldap.ScopeWholeSubtree}, "dc=example,dc=org;ou=People,dc=example,dc=org;uid=jdoe,ou=People,dc=example,dc=org;uid=asmith,ou=People,dc=example,dc=org;cn=admins,ou=Groups,dc=example,dc=org"},
+		{&ldap.SearchRequest{Scope: ldap.ScopeWholeSubtree, Filter: "(&(objectClass=posixAccount)(!(loginShell=/bin/bash)))"}, "uid=asmith,ou=People,dc=example,dc=org"},
+		{&ldap.SearchRequest{Scope: ldap.ScopeWholeSubtree, Filter: "(member=*)"}, "cn=admins,ou=Groups,dc=example,dc=org"},
+		{&ldap.SearchRequest{BaseDN: "OU=people,DC=example,DC=org", Scope: ldap.ScopeBaseObject}, "ou=People,dc=example,dc=org"},
+		{&ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeSingleLevel}, "ou=People,dc=example,dc=org"},
+		{&ldap.SearchRequest{BaseDN: "ou=People,dc=example,dc=org", Scope: ldap.ScopeWholeSubtree}, "ou=People,dc=example,dc=org;uid=jdoe,ou=People,dc=example,dc=org;uid=asmith,ou=People,dc=example,dc=org"},
+		{&ldap.SearchRequest{Scope: ldap.ScopeWholeSubtree, Filter: "(uid=*)", SizeLimit: 1}, "uid=jdoe,ou=People,dc=example,dc=org"},
+	} {
+		if got := dns(search(t, tc.req)); got != tc.expected {
+			t.Errorf("%+v: got %s, expected %s", tc.req, got, tc.expected)
+		}
+	}
+}
+
+func TestSearchAttributes(t *testing.T) {
+	attrs := func(req *ldap.SearchRequest) string {
+		req.Filter = "(uid=jdoe)"
+		req.Scope = ldap.ScopeWholeSubtree
+		entries := search(t, req)
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries", len(entries))
+		}
+		var names []string
+		for _, a := range entries[0].Attributes {
+			names = append(names, a.Name)
+			if req.TypesOnly && len(a.Values) != 0 {
+				t.Errorf("types only: %s has values", a.Name)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := attrs(&ldap.SearchRequest{Attributes: []string{"MAIL", "cn"}}); got != "cn,mail" {
+		t.Errorf("named: got %s", got)
+	}
+	if got := attrs(&ldap.SearchRequest{Attributes: []string{"+"}}); got != "createTimestamp" {
+		t.Errorf("operational: got %s", got)
+	}
+	if got := attrs(&ldap.SearchRequest{Attributes: []string{"*"}}); strings.Contains(got, "createTimestamp") || !strings.Contains(got, "uidNumber") {
+		t.Errorf("user: got %s", got)
+	}
+	if got := attrs(&ldap.SearchRequest{Attributes: []string{"1.1"}}); got != "" {
+		t.Errorf("none: got %s", got)
+	}
+	if got := attrs(&ldap.SearchRequest{Attributes: []string{"uid"}, TypesOnly: true}); got != "uid" {
+		t.Errorf("types only: got %s", got)
+	}
+}
@@ -0,0 +1,231 @@
+package ldif_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+// canonical returns a representation of the records which does not depend
+// on the order of the attributes or the line folding and encoding.
+func canonical(l *ldif.LDIF) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version %d\n", l.Version)
+	for _, e := range l.Entries {
+		switch {
+		case e.Entry != nil:
+			fmt.Fprintf(&b, "entry %q\n", e.Entry.DN)
+			var attrs []string
+			for _, a := range e.Entry.Attributes {
+				attrs = append(attrs, fmt.Sprintf("  %q %q\n", a.Name, a.Values))
+			}
+			slices.Sort(attrs)
+			b.WriteString(strings.Join(attrs, ""))
+		case e.Add != nil:
+			fmt.Fprintf(&b, "add %q\n", e.Add.DN)
+			var attrs []string
+			for _, a := range e.Add.Attributes {
+				attrs = append(attrs, fmt.Sprintf("  %q %q\n", a.Type, a.Vals))
+			}
+			slices.Sort(attrs)
+			b.WriteString(strings.Join(attrs, ""))
+		case e.Del != nil:
+			fmt.Fprintf(&b, "delete %q\n", e.Del.DN)
+		case e.Modify != nil:
+			fmt.Fprintf(&b, "modify %q\n", e.Modify.DN)
+			for _, c := range e.Modify.Changes {
+				fmt.Fprintf(&b, "  %d %q %q\n", c.Operation, c.Modification.Type, c.Modification.Vals)
+			}
+		case e.ModifyDN != nil:
+			fmt.Fprintf(&b, "moddn %q %q %v %q\n", e.ModifyDN.DN, e.ModifyDN.NewRDN, e.ModifyDN.DeleteOldRDN, e.ModifyDN.NewSuperior)
+		}
+	}
+	return b.String()
+}
+
+// checkRoundTrip checks that Parse → Marshal → Parse returns the same
+// records and that marshalling them again gives the same result.
+func checkRoundTrip(t *testing.T, l *ldif.LDIF) {
+	t.Helper()
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	l2, err := ldif.Parse(out)
+	if err != nil {
+		t.Fatalf("Parse of marshalled LDIF: %s\n%s", err, out)
+	}
+	if got, want := canonical(l2), canonical(l); got != want {
+		t.Fatalf("round trip changed the records:\ngot:\n%s\nwant:\n%s\nLDIF:\n%s", got, want, out)
+	}
+	out2, err := ldif.Marshal(l2)
+	if err != nil {
+		t.Fatalf("second Marshal: %s", err)
+	}
+	l3, err := ldif.Parse(out2)
+	if err != nil {
+		t.Fatalf("second Parse: %s", err)
+	}
+	if canonical(l3) != canonical(l2) {
+		t.Fatalf("round trip is not idempotent:\n%s", out2)
+	}
+}
+
+func corpusFiles(tb testing.TB) []string {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ldif"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(files) == 0 {
+		tb.Fatal("no corpus files found")
+	}
+	return files
+}
+
+func TestCorpusRoundTrip(t *testing.T) {
+	for _, file := range corpusFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := ldif.Parse(string(data))
+			if err != nil {
+				t.Fatalf("Parse: %s", err)
+			}
+			if len(l.Entries) == 0 {
+				t.Fatal("no entries parsed")
+			}
+			checkRoundTrip(t, l)
+		})
+	}
+}
+
+// marshalErrorsOK are the inputs which are parsed but can not be
+// marshalled: LDIF can not express these records.
+func marshalErrorsOK(l *ldif.LDIF) bool {
+	var content, changes bool
+	for _, e := range l.Entries {
+		switch {
+		case e.Entry != nil:
+			content = true
+			for _, a := range e.Entry.Attributes {
+				// "changetype" or "control" as the first attribute of a
+				// content record would be read as part of a change record
+				if strings.EqualFold(a.Name, "changetype") || strings.EqualFold(a.Name, "control") {
+					return true
+				}
+			}
+		case e.Modify != nil:
+			changes = true
+			for _, c := range e.Modify.Changes {
+				if c.Operation == ldap.AddAttribute && len(c.Modification.Vals) == 0 {
+					return true
+				}
+			}
+		default:
+			changes = true
+		}
+	}
+	return content && changes
+}
+
+func FuzzParse(f *testing.F) {
+	for _, file := range corpusFiles(f) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+	f.Add(personLDIF)
+	f.Add(ldifRFC2849Example6)
+	f.Add(ldifLenientModify)
+
+	f.Fuzz(func(t *testing.T, in string) {
+		for _, mode := range []ldif.Mode{ldif.ModeDefault, ldif.ModeStrict, ldif.ModeLenient} {
+			l := &ldif.LDIF{Mode: mode, Controls: true}
+			_ = ldif.Unmarshal(strings.NewReader(in), l)
+		}
+
+		l, err := ldif.Parse(in)
+		if err != nil || marshalErrorsOK(l) {
+			return
+		}
+		checkRoundTrip(t, l)
+	})
+}
+
+func FuzzMarshalValue(f *testing.F) {
+	f.Add("cn=foo,dc=example,dc=org", "plain value", 0)
+	f.Add("cn=foo", " leading space", 10)
+	f.Add("cn=foo", "trailing space ", 3)
+	f.Add("cn=föo", ":colon", 2)
+	f.Add("cn=foo", "<less than", -1)
+	f.Add("cn=foo", "line\nbreak\r\n", 76)
+	f.Add(" cn=foo", "\x00\xff", 1)
+	f.Add("cn=foo", strings.Repeat("long value ", 50), 5)
+
+	f.Fuzz(func(t *testing.T, dn, value string, fw int) {
+		fw %= 200
+		entry := ldap.NewEntry(dn, map[string][]string{"description": {value}})
+		l := &ldif.LDIF{Entries: []*ldif.Entry{{Entry: entry}}, FoldWidth: fw}
+		out, err := ldif.Marshal(l)
+		if err != nil {
+			t.Fatalf("Marshal: %s", err)
+		}
+		if fw > 1 {
+			for _, line := range strings.Split(out, "\n") {
+				if len(line) > fw {
+					t.Fatalf("line longer than %d: %q", fw, line)
+				}
+			}
+		}
+		l2, err := ldif.Parse(out)
+		if err != nil {
+			t.Fatalf("Parse: %s\n%s", err, out)
+		}
+		if len(l2.Entries) != 1 || l2.Entries[0].Entry == nil {
+			t.Fatalf("expected one entry:\n%s", out)
+		}
+		got := l2.Entries[0].Entry
+		if got.DN != dn {
+			t.Errorf("got DN %q, expected %q", got.DN, dn)
+		}
+		if vals := got.GetAttributeValues("description"); len(vals) != 1 || vals[0] != value {
+			t.Errorf("got values %q, expected %q", vals, value)
+		}
+	})
+}
+
+func FuzzBase64Value(f *testing.F) {
+	f.Add([]byte("plain value"))
+	f.Add([]byte{})
+	f.Add([]byte("\x00\xff\xfe"))
+	f.Add([]byte(strings.Repeat("long value ", 50)))
+
+	f.Fuzz(func(t *testing.T, value []byte) {
+		enc := base64.StdEncoding.EncodeToString(value)
+		in := "dn: cn=foo\ndescription:: " + enc + "\ndescription:: " + enc + "\n\n" +
+			"dn: cn=bar\ndescription:: " + base64.StdEncoding.EncodeToString(append([]byte("x"), value...)) + "\n"
+		var got []string
+		for e, err := range ldif.UnmarshalEntries(strings.NewReader(in), &ldif.LDIF{}) {
+			if err != nil {
+				t.Fatalf("UnmarshalEntries: %s\n%s", err, in)
+			}
+			got = append(got, e.Entry.GetAttributeValues("description")...)
+		}
+		// the values must not share memory with each other or the input
+		want := []string{string(value), string(value), "x" + string(value)}
+		if !slices.Equal(got, want) {
+			t.Fatalf("got values %q, expected %q", got, want)
+		}
+	})
+}
@@ -2,6 +2,9 @@ module github.com/go-ldap/ldif
 
 go 1.23
 
-require github.com/go-ldap/ldap/v3 v3.1.7
-
-require github.com/go-asn1-ber/asn1-ber v1.4.1 // indirect
+require (
+	github.com/go-asn1-ber/asn1-ber v1.4.1
+	github.com/go-ldap/ldap/v3 v3.1.7
+	github.com/klauspost/compress v1.18.4
+	gopkg.in/yaml.v3 v3.0.1
+)
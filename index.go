@@ -0,0 +1,356 @@
+package ldif
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrNotFound is returned by Index.Lookup if the DN is not in the index.
+var ErrNotFound = errors.New("entry not found in index")
+
+const indexHeader = "# ldif index v1"
+
+type indexRecord struct {
+	dn     string
+	offset int64
+	length int64
+}
+
+// An Index allows random access to the records of a (large, uncompressed)
+// LDIF file by DN. It records the byte offset and length of each record,
+// keyed by the normalized DN, so a record can be read and parsed without
+// reading the file from the start.
+//
+// If a DN appears more than once in the file, the first record is used.
+type Index struct {
+	r        io.ReaderAt
+	size     int64
+	records  []indexRecord
+	byDN     map[string]int
+	children map[string][]int
+	closer   io.Closer
+}
+
+// BuildIndex scans the LDIF in r (of the given size) once and returns the
+// index of its records.
+func BuildIndex(r io.ReaderAt, size int64) (*Index, error) {
+	ix := &Index{r: r, size: size}
+
+	reader := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	parser := &LDIF{}
+	var (
+		off, recStart, recEnd int64
+		curLine, recLine      int
+		inRecord, inComment   bool
+		collectingDN, dnDone  bool
+		dnLine                string
+		firstRecord           = true
+	)
+
+	finish := func() error {
+		defer func() {
+			inRecord, inComment, collectingDN, dnDone = false, false, false, false
+			dnLine = ""
+			firstRecord = false
+		}()
+		if dnLine == "" {
+			if firstRecord {
+				// a "version: 1" on its own
+				return nil
+			}
+			return newParseError(recLine, errorf(ErrMissingDN, "missing 'dn:'"))
+		}
+		_, dn, err := parser.parseLine(dnLine)
+		if err != nil {
+			return newParseError(recLine, err)
+		}
+		ix.records = append(ix.records, indexRecord{dn: dn, offset: recStart, length: recEnd - recStart})
+		return nil
+	}
+
+	for {
+		line, err := reader.ReadString(lf)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		curLine++
+		trimmed := strings.TrimRight(line, sep)
+
+		switch {
+		case len(trimmed) == 0:
+			if inRecord {
+				if ferr := finish(); ferr != nil {
+					return nil, ferr
+				}
+			}
+
+		default:
+			if !inRecord {
+				inRecord = true
+				recStart = off
+				recLine = curLine
+			}
+			recEnd = off + int64(len(line))
+
+			switch trimmed[0] {
+			case comment:
+				inComment = true
+				if collectingDN {
+					collectingDN, dnDone = false, true
+				}
+			case space:
+				if !inComment && collectingDN {
+					dnLine += trimmed[1:]
+				}
+			default:
+				inComment = false
+				if collectingDN {
+					collectingDN, dnDone = false, true
+				}
+				if !dnDone && !collectingDN {
+					switch {
+					case firstRecord && strings.HasPrefix(trimmed, "version:"):
+					case strings.HasPrefix(trimmed, "dn:"):
+						collectingDN = true
+						dnLine = trimmed
+					default:
+						return nil, newParseError(curLine, errorf(ErrMissingDN, "missing 'dn:'"))
+					}
+				}
+			}
+		}
+		off += int64(len(line))
+		if err == io.EOF {
+			break
+		}
+	}
+	if inRecord {
+		if err := finish(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ix.buildMaps(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+func (ix *Index) buildMaps() error {
+	ix.byDN = make(map[string]int, len(ix.records))
+	ix.children = make(map[string][]int)
+	for i, rec := range ix.records {
+		parsed, err := ldap.ParseDN(rec.dn)
+		if err != nil {
+			return fmt.Errorf("invalid DN %q: %s", rec.dn, err)
+		}
+		key := normalizeParsedDN(parsed)
+		if _, ok := ix.byDN[key]; ok {
+			continue
+		}
+		ix.byDN[key] = i
+		if len(parsed.RDNs) > 0 {
+			parent := parentDN(parsed)
+			ix.children[parent] = append(ix.children[parent], i)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of records in the index.
+func (ix *Index) Len() int {
+	return len(ix.records)
+}
+
+// Lookup reads and parses the record with the given DN. If the DN is not
+// in the index, ErrNotFound is returned.
+func (ix *Index) Lookup(dn string) (*Entry, error) {
+	key, err := normalizeDN(dn)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := ix.byDN[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ix.read(ix.records[i])
+}
+
+// Children reads and parses the records which are direct children of the
+// given DN, in file order.
+func (ix *Index) Children(dn string) ([]*Entry, error) {
+	key, err := normalizeDN(dn)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, i := range ix.children[key] {
+		e, err := ix.read(ix.records[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (ix *Index) read(rec indexRecord) (*Entry, error) {
+	section := io.NewSectionReader(ix.r, rec.offset, rec.length)
+	for e, err := range UnmarshalEntries(section, &LDIF{}) {
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("no record found at offset %d", rec.offset)
+}
+
+// WriteTo writes the index to w, it can be read back with ReadIndex.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	c, err := fmt.Fprintf(bw, "%s\nsize %d\n", indexHeader, ix.size)
+	n += int64(c)
+	if err != nil {
+		return n, err
+	}
+	for _, rec := range ix.records {
+		c, err := fmt.Fprintf(bw, "%d %d %s\n", rec.offset, rec.length, strconv.Quote(rec.dn))
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// ReadIndex reads an index written by Index.WriteTo from idx for the LDIF
+// in r of the given size. An error is returned if the index was built for
+// a file of a different size.
+func ReadIndex(r io.ReaderAt, size int64, idx io.Reader) (*Index, error) {
+	ix := &Index{r: r, size: size}
+	scanner := bufio.NewScanner(idx)
+	scanner.Buffer(nil, 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		switch lineNo {
+		case 1:
+			if line != indexHeader {
+				return nil, errors.New("not an LDIF index")
+			}
+			continue
+		case 2:
+			indexSize, err := strconv.ParseInt(strings.TrimPrefix(line, "size "), 10, 64)
+			if err != nil || !strings.HasPrefix(line, "size ") {
+				return nil, fmt.Errorf("invalid size in line %d of index", lineNo)
+			}
+			if indexSize != size {
+				return nil, fmt.Errorf("index is for a file of %d bytes, not %d", indexSize, size)
+			}
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid line %d of index", lineNo)
+		}
+		offset, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in line %d of index: %s", lineNo, err)
+		}
+		length, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid length in line %d of index: %s", lineNo, err)
+		}
+		dn, err := strconv.Unquote(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid DN in line %d of index: %s", lineNo, err)
+		}
+		if offset < 0 || length < 0 || offset+length > size {
+			return nil, fmt.Errorf("record in line %d of index is out of range", lineNo)
+		}
+		ix.records = append(ix.records, indexRecord{dn: dn, offset: offset, length: length})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNo < 2 {
+		return nil, errors.New("not an LDIF index")
+	}
+	if err := ix.buildMaps(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// OpenIndex opens the given LDIF file for random access. The index is read
+// from the sidecar file path + ".idx" if it exists and is not older than the
+// LDIF, otherwise the index is built and written to the sidecar file.
+//
+// The returned index must be closed to close the LDIF file.
+func OpenIndex(path string) (*Index, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+
+	sidecar := path + ".idx"
+	if si, err := os.Stat(sidecar); err == nil && !si.ModTime().Before(fi.ModTime()) {
+		if idx, err := os.Open(sidecar); err == nil {
+			ix, err := ReadIndex(fh, fi.Size(), idx)
+			idx.Close()
+			if err == nil {
+				ix.closer = fh
+				return ix, nil
+			}
+		}
+	}
+
+	ix, err := BuildIndex(fh, fi.Size())
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	ix.closer = fh
+
+	out, err := os.Create(sidecar)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	if _, err := ix.WriteTo(out); err != nil {
+		out.Close()
+		fh.Close()
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Close closes the LDIF file opened by OpenIndex. For an index returned by
+// BuildIndex or ReadIndex it does nothing.
+func (ix *Index) Close() error {
+	if ix.closer == nil {
+		return nil
+	}
+	return ix.closer.Close()
+}
@@ -0,0 +1,125 @@
+package ldif_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var indexLDIF = `version: 1
+
+# the base
+dn: dc=example,dc=org
+objectClass: domain
+dc: example
+
+dn: ou=People,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+
+dn:: dWlkPWrDtnJnLG91PVBlb3BsZSxkYz1leGFtcGxlLGRjPW9yZw==
+objectClass: person
+uid: j` + "\xc3\xb6" + `rg
+
+dn: uid=someone,ou=people,
+ dc=example,dc=org
+objectClass: person
+uid: someone
+description: a long
+  folded value
+`
+
+func TestIndexLookup(t *testing.T) {
+	r := strings.NewReader(indexLDIF)
+	ix, err := ldif.BuildIndex(r, r.Size())
+	if err != nil {
+		t.Fatalf("BuildIndex: %s", err)
+	}
+	if ix.Len() != 4 {
+		t.Fatalf("expected 4 records, got %d", ix.Len())
+	}
+
+	e, err := ix.Lookup("UID=Someone, OU=People, DC=Example, DC=Org")
+	if err != nil {
+		t.Fatalf("Lookup: %s", err)
+	}
+	if got := e.Entry.GetAttributeValue("description"); got != "a long folded value" {
+		t.Errorf("wrong description: %q", got)
+	}
+
+	e, err = ix.Lookup("uid=j\xc3\xb6rg,ou=People,dc=example,dc=org")
+	if err != nil {
+		t.Fatalf("Lookup of base64 DN: %s", err)
+	}
+	if e.Entry.GetAttributeValue("uid") != "j\xc3\xb6rg" {
+		t.Errorf("wrong entry: %#v", e.Entry)
+	}
+
+	if _, err := ix.Lookup("uid=nobody,dc=example,dc=org"); !errors.Is(err, ldif.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	children, err := ix.Children("ou=people,dc=example,dc=org")
+	if err != nil {
+		t.Fatalf("Children: %s", err)
+	}
+	if len(children) != 2 || children[1].Entry.GetAttributeValue("uid") != "someone" {
+		t.Errorf("wrong children: %#v", children)
+	}
+}
+
+func TestIndexPersist(t *testing.T) {
+	r := strings.NewReader(indexLDIF)
+	ix, err := ldif.BuildIndex(r, r.Size())
+	if err != nil {
+		t.Fatalf("BuildIndex: %s", err)
+	}
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+	ix2, err := ldif.ReadIndex(r, r.Size(), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadIndex: %s", err)
+	}
+	if _, err := ix2.Lookup("dc=example,dc=org"); err != nil {
+		t.Errorf("Lookup: %s", err)
+	}
+	if _, err := ldif.ReadIndex(r, r.Size()+1, bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected error for index of a different file size")
+	}
+}
+
+func TestOpenIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.ldif")
+	if err := os.WriteFile(path, []byte(indexLDIF), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // second run reads the sidecar file
+		ix, err := ldif.OpenIndex(path)
+		if err != nil {
+			t.Fatalf("OpenIndex: %s", err)
+		}
+		if _, err := ix.Lookup("ou=people,dc=example,dc=org"); err != nil {
+			t.Errorf("Lookup: %s", err)
+		}
+		ix.Close()
+		if _, err := os.Stat(path + ".idx"); err != nil {
+			t.Errorf("sidecar file missing: %s", err)
+		}
+	}
+}
+
+func TestBuildIndexMissingDN(t *testing.T) {
+	r := strings.NewReader("dn: dc=example,dc=org\ndc: example\n\ncn: foo\n")
+	_, err := ldif.BuildIndex(r, r.Size())
+	var perr *ldif.ParseError
+	if !errors.As(err, &perr) || perr.Line != 4 {
+		t.Errorf("expected parse error in line 4, got %v", err)
+	}
+}
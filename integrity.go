@@ -0,0 +1,220 @@
+package ldif
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// referenceAttributes are the attributes checked by CheckIntegrity by
+// default.
+var referenceAttributes = []string{"member", "uniqueMember", "manager", "owner", "memberOf"}
+
+// IntegrityOptions configure CheckIntegrity.
+type IntegrityOptions struct {
+	// Attributes are the DN-valued attributes whose values must reference
+	// an entry, by default member, uniqueMember, manager, owner and
+	// memberOf.
+	Attributes []string
+	// ExternalSuffixes are the DNs of trees outside the LDIF, references
+	// to them or to entries below them are not checked.
+	ExternalSuffixes []string
+}
+
+// An IntegrityProblem is a reference to an entry which is not in the LDIF.
+type IntegrityProblem struct {
+	// Line is the first line of the record, 0 if it is not known.
+	Line int
+	// DN is the DN of the entry with the problem.
+	DN string
+	// Attribute is the attribute with the dangling reference, it is empty
+	// for a missing parent.
+	Attribute string
+	// Value is the referenced DN or the DN of the missing parent.
+	Value string
+}
+
+func (p *IntegrityProblem) String() string {
+	if p.Attribute == "" {
+		return fmt.Sprintf("parent %q of %q does not exist", p.Value, p.DN)
+	}
+	return fmt.Sprintf("%s %q of %q does not exist", p.Attribute, p.Value, p.DN)
+}
+
+// integrityChecker holds the normalized DNs of all entries.
+type integrityChecker struct {
+	attrs    map[string]bool
+	external [][]string // normalized RDNs
+	dns      map[string]bool
+}
+
+func newIntegrityChecker(opts *IntegrityOptions) (*integrityChecker, error) {
+	if opts == nil {
+		opts = &IntegrityOptions{}
+	}
+	attrs := opts.Attributes
+	if attrs == nil {
+		attrs = referenceAttributes
+	}
+	c := &integrityChecker{attrs: make(map[string]bool), dns: make(map[string]bool)}
+	for _, a := range attrs {
+		c.attrs[strings.ToLower(a)] = true
+	}
+	for _, s := range opts.ExternalSuffixes {
+		dn, err := ldap.ParseDN(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, s, err)
+		}
+		c.external = append(c.external, rdnKeys(dn))
+	}
+	return c, nil
+}
+
+// add records the DN of the content or add record.
+func (c *integrityChecker) add(record *Entry) error {
+	entry := contentEntry(record)
+	if entry == nil {
+		return fmt.Errorf("%s is a change record, the integrity check needs content records", record.DN())
+	}
+	key, err := normalizeDN(entry.DN)
+	if err != nil {
+		return fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN, err)
+	}
+	c.dns[key] = true
+	return nil
+}
+
+// exists reports whether the DN is in the LDIF or within an external
+// suffix.
+func (c *integrityChecker) exists(dn *ldap.DN) bool {
+	if c.dns[normalizeParsedDN(dn)] {
+		return true
+	}
+	for _, s := range c.external {
+		if hasSuffix(dn, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the problems of the record, which was added before.
+func (c *integrityChecker) check(record *Entry, line int) []*IntegrityProblem {
+	entry := contentEntry(record)
+	var problems []*IntegrityProblem
+	dn, _ := ldap.ParseDN(entry.DN)
+	// the parent is only required, if there is an entry above it
+	if len(dn.RDNs) > 1 {
+		parent := &ldap.DN{RDNs: dn.RDNs[1:]}
+		if !c.exists(parent) {
+			for i := 2; i < len(dn.RDNs); i++ {
+				if c.exists(&ldap.DN{RDNs: dn.RDNs[i:]}) {
+					problems = append(problems, &IntegrityProblem{Line: line, DN: entry.DN, Value: dnString(parent)})
+					break
+				}
+			}
+		}
+	}
+	for _, a := range entry.Attributes {
+		name, _, _ := strings.Cut(a.Name, ";")
+		if !c.attrs[strings.ToLower(name)] {
+			continue
+		}
+		for _, v := range a.Values {
+			if ref, err := ldap.ParseDN(v); err == nil && c.exists(ref) {
+				continue
+			}
+			problems = append(problems, &IntegrityProblem{Line: line, DN: entry.DN, Attribute: a.Name, Value: v})
+		}
+	}
+	return problems
+}
+
+// CheckIntegrity checks the references between the content records of the
+// LDIF: the values of the DN-valued attributes must be the DN of an entry
+// in the LDIF or within one of the external suffixes, and the parent of an
+// entry must exist if there is an entry above it. Invalid DNs in these
+// attributes are reported as dangling references, too.
+//
+// Add records are checked like content records, other change records are
+// an error.
+func CheckIntegrity(l *LDIF, opts *IntegrityOptions) ([]*IntegrityProblem, error) {
+	c, err := newIntegrityChecker(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range l.Entries {
+		if err := c.add(e); err != nil {
+			return nil, err
+		}
+	}
+	var problems []*IntegrityProblem
+	for _, e := range l.Entries {
+		problems = append(problems, c.check(e, 0)...)
+	}
+	return problems, nil
+}
+
+// CheckIntegrityReader is CheckIntegrity for an LDIF which is read twice
+// from r instead of being loaded, the first pass collects the DNs, the
+// second checks the references. The records are parsed as configured in l.
+// The problems have the line numbers of the records.
+func CheckIntegrityReader(r io.ReadSeeker, l *LDIF, opts *IntegrityOptions) ([]*IntegrityProblem, error) {
+	c, err := newIntegrityChecker(opts)
+	if err != nil {
+		return nil, err
+	}
+	pass := func(yield func(*Entry) error) error {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		for e, err := range UnmarshalEntries(r, l) {
+			if err != nil {
+				return err
+			}
+			if err := yield(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := pass(c.add); err != nil {
+		return nil, err
+	}
+	var problems []*IntegrityProblem
+	err = pass(func(e *Entry) error {
+		problems = append(problems, c.check(e, l.recordLine)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// IntegrityFixes returns the modify records which delete the dangling
+// references of the problems, one per entry. Missing parents cannot be
+// fixed this way, they are skipped.
+func IntegrityFixes(problems []*IntegrityProblem) []*Entry {
+	var fixes []*Entry
+	mods := make(map[string]*ldap.ModifyRequest)
+	for _, p := range problems {
+		if p.Attribute == "" {
+			continue
+		}
+		mod, ok := mods[p.DN]
+		if !ok {
+			mod = ldap.NewModifyRequest(p.DN, nil)
+			mods[p.DN] = mod
+			fixes = append(fixes, &Entry{Modify: mod})
+		}
+		if n := len(mod.Changes); n > 0 && mod.Changes[n-1].Modification.Type == p.Attribute {
+			mod.Changes[n-1].Modification.Vals = append(mod.Changes[n-1].Modification.Vals, p.Value)
+			continue
+		}
+		mod.Delete(p.Attribute, []string{p.Value})
+	}
+	return fixes
+}
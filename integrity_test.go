@@ -0,0 +1,98 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var integrityLDIF = `dn: dc=example,dc=org
+objectClass: domain
+dc: example
+
+dn: ou=People,dc=example,dc=org
+objectClass: organizationalUnit
+ou: People
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: jdoe
+manager: uid=gone,ou=People,dc=example,dc=org
+
+dn: cn=admins,ou=Groups,dc=example,dc=org
+objectClass: groupOfNames
+cn: admins
+member: UID=JDoe,ou=People,dc=example,dc=org
+member: uid=gone,ou=People,dc=example,dc=org
+member: cn=svc,ou=Services,o=external
+member: not a DN
+seeAlso: cn=unchecked,dc=example,dc=org
+`
+
+func problemStrings(problems []*ldif.IntegrityProblem) []string {
+	var s []string
+	for _, p := range problems {
+		s = append(s, p.String())
+	}
+	return s
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	expected := []string{
+		`manager "uid=gone,ou=People,dc=example,dc=org" of "uid=jdoe,ou=People,dc=example,dc=org" does not exist`,
+		`parent "ou=Groups,dc=example,dc=org" of "cn=admins,ou=Groups,dc=example,dc=org" does not exist`,
+		`member "uid=gone,ou=People,dc=example,dc=org" of "cn=admins,ou=Groups,dc=example,dc=org" does not exist`,
+		`member "not a DN" of "cn=admins,ou=Groups,dc=example,dc=org" does not exist`,
+	}
+	opts := &ldif.IntegrityOptions{ExternalSuffixes: []string{"O=External"}}
+
+	l, err := ldif.Parse(integrityLDIF)
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	problems, err := ldif.CheckIntegrity(l, opts)
+	if err != nil {
+		t.Fatalf("check: %s", err)
+	}
+	if got := strings.Join(problemStrings(problems), "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+
+	problems, err = ldif.CheckIntegrityReader(strings.NewReader(integrityLDIF), &ldif.LDIF{}, opts)
+	if err != nil {
+		t.Fatalf("check reader: %s", err)
+	}
+	if got := strings.Join(problemStrings(problems), "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("reader: got:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+	if len(problems) == 4 && (problems[0].Line != 9 || problems[1].Line != 14) {
+		t.Errorf("wrong lines %d and %d", problems[0].Line, problems[1].Line)
+	}
+
+	fixes, err := ldif.Marshal(&ldif.LDIF{Entries: ldif.IntegrityFixes(problems)})
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	expectedFixes := `dn: uid=jdoe,ou=People,dc=example,dc=org
+changetype: modify
+delete: manager
+manager: uid=gone,ou=People,dc=example,dc=org
+-
+
+dn: cn=admins,ou=Groups,dc=example,dc=org
+changetype: modify
+delete: member
+member: uid=gone,ou=People,dc=example,dc=org
+member: not a DN
+-
+
+`
+	if fixes != expectedFixes {
+		t.Errorf("fixes: got:\n%s\nexpected:\n%s", fixes, expectedFixes)
+	}
+
+	if _, err := ldif.CheckIntegrityReader(strings.NewReader("dn: cn=foo\nchangetype: delete\n"), &ldif.LDIF{}, nil); err == nil {
+		t.Error("change record: expected an error")
+	}
+}
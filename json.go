@@ -0,0 +1,334 @@
+package ldif
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// JSONRecord is the JSON representation of one Entry. A content record is
+//
+//	{
+//	  "dn": "uid=someone,dc=example,dc=org",
+//	  "attributes": [
+//	    {"name": "cn", "values": ["Someone"]},
+//	    {"name": "jpegPhoto", "values": ["/9j/4AAQ..."], "base64": true}
+//	  ]
+//	}
+//
+// Change records additionally have a "changetype" of "add", "delete",
+// "modify" or "moddn" and may carry "controls". The changes of a modify
+// record are listed in "changes", each with an "operation" of "add",
+// "delete", "replace" or "increment" and the attribute name and values as
+// above. A moddn record has "newrdn", "deleteoldrdn" and an optional
+// "newsuperior".
+//
+// The values of an attribute are base64 encoded (and flagged with
+// "base64": true) when at least one of them is not valid UTF-8. The same
+// applies to control values.
+type JSONRecord struct {
+	DN         string          `json:"dn"`
+	ChangeType string          `json:"changetype,omitempty"`
+	Controls   []JSONControl   `json:"controls,omitempty"`
+	Attributes []JSONAttribute `json:"attributes,omitempty"`
+	Changes    []JSONChange    `json:"changes,omitempty"`
+
+	NewRDN       string `json:"newrdn,omitempty"`
+	DeleteOldRDN bool   `json:"deleteoldrdn,omitempty"`
+	NewSuperior  string `json:"newsuperior,omitempty"`
+}
+
+// JSONAttribute is an attribute with its values in a JSONRecord.
+type JSONAttribute struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+	Base64 bool     `json:"base64,omitempty"`
+}
+
+// JSONChange is one modification of a modify record in a JSONRecord.
+type JSONChange struct {
+	Operation string `json:"operation"`
+	JSONAttribute
+}
+
+// JSONControl is a control of a change record in a JSONRecord.
+type JSONControl struct {
+	OID         string `json:"oid"`
+	Criticality bool   `json:"criticality,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Base64      bool   `json:"base64,omitempty"`
+}
+
+var modifyOps = []string{
+	ldap.AddAttribute:       "add",
+	ldap.DeleteAttribute:    "delete",
+	ldap.ReplaceAttribute:   "replace",
+	ldap.IncrementAttribute: "increment",
+}
+
+// ToJSON returns the JSON representation of the given entries: an array of
+// JSONRecord objects, indented for readable diffs. The attribute and value
+// order of the entries is kept.
+func ToJSON(entries []*Entry) ([]byte, error) {
+	records := make([]*JSONRecord, 0, len(entries))
+	for _, e := range entries {
+		rec, err := e.toJSONRecord()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromJSON parses entries from a JSON array of JSONRecord objects as returned
+// by ToJSON.
+func FromJSON(data []byte) ([]*Entry, error) {
+	var records []*JSONRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, len(records))
+	for i, rec := range records {
+		if rec == nil {
+			return nil, fmt.Errorf("record %d: null record", i)
+		}
+		e, err := rec.toEntry()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", i, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface, the entry is encoded
+// as a JSONRecord.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	rec, err := e.toJSONRecord()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rec)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, see JSONRecord
+// for the format.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var rec JSONRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	entry, err := rec.toEntry()
+	if err != nil {
+		return err
+	}
+	*e = *entry
+	return nil
+}
+
+func (e *Entry) toJSONRecord() (*JSONRecord, error) {
+	if e == nil {
+		return nil, errors.New("nil entry")
+	}
+	switch {
+	case e.Entry != nil:
+		rec := &JSONRecord{DN: e.Entry.DN}
+		for _, attr := range e.Entry.Attributes {
+			rec.Attributes = append(rec.Attributes, newJSONAttribute(attr.Name, attr.Values))
+		}
+		return rec, nil
+
+	case e.Add != nil:
+		rec := &JSONRecord{DN: e.Add.DN, ChangeType: "add", Controls: jsonControls(e.Add.Controls)}
+		for _, attr := range e.Add.Attributes {
+			rec.Attributes = append(rec.Attributes, newJSONAttribute(attr.Type, attr.Vals))
+		}
+		return rec, nil
+
+	case e.Del != nil:
+		return &JSONRecord{DN: e.Del.DN, ChangeType: "delete", Controls: jsonControls(e.Del.Controls)}, nil
+
+	case e.Modify != nil:
+		rec := &JSONRecord{DN: e.Modify.DN, ChangeType: "modify", Controls: jsonControls(e.Modify.Controls)}
+		for _, ch := range e.Modify.Changes {
+			if int(ch.Operation) >= len(modifyOps) {
+				return nil, fmt.Errorf("invalid operation %d in modify request for %s", ch.Operation, e.Modify.DN)
+			}
+			rec.Changes = append(rec.Changes, JSONChange{
+				Operation:     modifyOps[ch.Operation],
+				JSONAttribute: newJSONAttribute(ch.Modification.Type, ch.Modification.Vals),
+			})
+		}
+		return rec, nil
+
+	case e.ModifyDN != nil:
+		return &JSONRecord{
+			DN:           e.ModifyDN.DN,
+			ChangeType:   "moddn",
+			NewRDN:       e.ModifyDN.NewRDN,
+			DeleteOldRDN: e.ModifyDN.DeleteOldRDN,
+			NewSuperior:  e.ModifyDN.NewSuperior,
+		}, nil
+	}
+	return nil, errors.New("empty entry")
+}
+
+func (rec *JSONRecord) toEntry() (*Entry, error) {
+	controls, err := rec.controls()
+	if err != nil {
+		return nil, err
+	}
+	switch rec.ChangeType {
+	case "":
+		if len(controls) != 0 {
+			return nil, errors.New("controls found without changetype")
+		}
+		if len(rec.Changes) != 0 {
+			return nil, errors.New("changes found without changetype")
+		}
+		entry := &ldap.Entry{DN: rec.DN}
+		for _, attr := range rec.Attributes {
+			vals, err := attr.values()
+			if err != nil {
+				return nil, err
+			}
+			entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(attr.Name, vals))
+		}
+		return &Entry{Entry: entry}, nil
+
+	case "add":
+		add := ldap.NewAddRequest(rec.DN, controls)
+		for _, attr := range rec.Attributes {
+			vals, err := attr.values()
+			if err != nil {
+				return nil, err
+			}
+			add.Attribute(attr.Name, vals)
+		}
+		return &Entry{Add: add}, nil
+
+	case "delete":
+		if len(rec.Attributes) != 0 || len(rec.Changes) != 0 {
+			return nil, errors.New("no attributes allowed for changetype delete")
+		}
+		return &Entry{Del: ldap.NewDelRequest(rec.DN, controls)}, nil
+
+	case "modify":
+		mod := ldap.NewModifyRequest(rec.DN, controls)
+		for _, ch := range rec.Changes {
+			vals, err := ch.values()
+			if err != nil {
+				return nil, err
+			}
+			switch ch.Operation {
+			case "add":
+				mod.Add(ch.Name, vals)
+			case "delete":
+				mod.Delete(ch.Name, vals)
+			case "replace":
+				mod.Replace(ch.Name, vals)
+			case "increment":
+				if len(vals) != 1 {
+					return nil, fmt.Errorf("increment of %s requires exactly one value", ch.Name)
+				}
+				mod.Increment(ch.Name, vals[0])
+			default:
+				return nil, fmt.Errorf("invalid operation %s in modify request", ch.Operation)
+			}
+		}
+		return &Entry{Modify: mod}, nil
+
+	case "moddn", "modrdn":
+		if len(controls) != 0 {
+			return nil, fmt.Errorf("controls are not supported for changetype %s", rec.ChangeType)
+		}
+		if rec.NewRDN == "" {
+			return nil, fmt.Errorf("changetype %s requires newrdn", rec.ChangeType)
+		}
+		return &Entry{ModifyDN: ldap.NewModifyDNRequest(rec.DN, rec.NewRDN, rec.DeleteOldRDN, rec.NewSuperior)}, nil
+	}
+	return nil, fmt.Errorf("invalid changetype %s", rec.ChangeType)
+}
+
+func (rec *JSONRecord) controls() ([]ldap.Control, error) {
+	var controls []ldap.Control
+	for _, c := range rec.Controls {
+		if err := validOID(c.OID); err != nil || c.OID == "" {
+			return nil, fmt.Errorf("%s is not a valid oid", c.OID)
+		}
+		value := []byte(c.Value)
+		if c.Base64 {
+			dec, err := base64.StdEncoding.DecodeString(c.Value)
+			if err != nil {
+				return nil, err
+			}
+			value = dec
+		}
+		controls = append(controls, newControl(c.OID, c.Criticality, value))
+	}
+	return controls, nil
+}
+
+func newJSONAttribute(name string, values []string) JSONAttribute {
+	attr := JSONAttribute{Name: name, Values: values}
+	if attr.Values == nil {
+		attr.Values = []string{}
+	}
+	for _, v := range values {
+		if !utf8.ValidString(v) {
+			attr.Base64 = true
+			break
+		}
+	}
+	if attr.Base64 {
+		attr.Values = make([]string, len(values))
+		for i, v := range values {
+			attr.Values[i] = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+	}
+	return attr
+}
+
+func (a JSONAttribute) values() ([]string, error) {
+	if !a.Base64 {
+		return a.Values, nil
+	}
+	vals := make([]string, len(a.Values))
+	for i, v := range a.Values {
+		dec, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value for %s: %s", a.Name, err)
+		}
+		vals[i] = string(dec)
+	}
+	return vals, nil
+}
+
+func jsonControls(controls []ldap.Control) []JSONControl {
+	var out []JSONControl
+	for _, c := range controls {
+		oid, criticality, value := controlParts(c)
+		jc := JSONControl{OID: oid, Criticality: criticality}
+		if utf8.Valid(value) {
+			jc.Value = string(value)
+		} else {
+			jc.Value = base64.StdEncoding.EncodeToString(value)
+			jc.Base64 = true
+		}
+		out = append(out, jc)
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package ldif_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+func TestToJSONContent(t *testing.T) {
+	l, err := ldif.Parse("dn: uid=someone,dc=example,dc=org\ncn: Someone\njpegPhoto:: /9j/AA==\n")
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	data, err := ldif.ToJSON(l.Entries)
+	if err != nil {
+		t.Fatalf("ToJSON: %s", err)
+	}
+	expected := `[
+  {
+    "dn": "uid=someone,dc=example,dc=org",
+    "attributes": [
+      {
+        "name": "cn",
+        "values": [
+          "Someone"
+        ]
+      },
+      {
+        "name": "jpegPhoto",
+        "values": [
+          "/9j/AA=="
+        ],
+        "base64": true
+      }
+    ]
+  }
+]
+`
+	if string(data) != expected {
+		t.Errorf("wrong JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	entries, err := ldif.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON: %s", err)
+	}
+	if got := entries[0].Entry.GetAttributeValue("jpegPhoto"); got != "\xff\xd8\xff\x00" {
+		t.Errorf("wrong jpegPhoto value: %q", got)
+	}
+}
+
+func TestJSONChangeRecordsRoundTrip(t *testing.T) {
+	mod := ldap.NewModifyRequest("uid=someone,dc=example,dc=org", []ldap.Control{ldap.NewControlManageDsaIT(true)})
+	mod.Replace("mail", []string{"someone@example.org"})
+	mod.Delete("description", nil)
+	mod.Increment("uidNumber", "1")
+	add := ldap.NewAddRequest("uid=other,dc=example,dc=org", nil)
+	add.Attribute("cn", []string{"Other"})
+	in := []*ldif.Entry{
+		{Add: add},
+		{Del: ldap.NewDelRequest("uid=gone,dc=example,dc=org", nil)},
+		{Modify: mod},
+		{ModifyDN: ldap.NewModifyDNRequest("uid=other,dc=example,dc=org", "uid=renamed", true, "")},
+	}
+
+	data, err := ldif.ToJSON(in)
+	if err != nil {
+		t.Fatalf("ToJSON: %s", err)
+	}
+	out, err := ldif.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON: %s", err)
+	}
+	again, err := ldif.ToJSON(out)
+	if err != nil {
+		t.Fatalf("ToJSON: %s", err)
+	}
+	if string(data) != string(again) {
+		t.Errorf("JSON round trip not stable:\n%s\n%s", data, again)
+	}
+	if out[2].Modify == nil || len(out[2].Modify.Changes) != 3 {
+		t.Fatalf("wrong modify record: %#v", out[2])
+	}
+	if out[2].Modify.Changes[2].Operation != ldap.IncrementAttribute {
+		t.Errorf("wrong operation: %d", out[2].Modify.Changes[2].Operation)
+	}
+	ctrl, ok := out[2].Modify.Controls[0].(*ldap.ControlManageDsaIT)
+	if !ok || !ctrl.Criticality {
+		t.Errorf("wrong control: %#v", out[2].Modify.Controls[0])
+	}
+	if out[3].ModifyDN == nil || *out[3].ModifyDN != *in[3].ModifyDN {
+		t.Errorf("wrong moddn record: %#v", out[3])
+	}
+	if out[1].Del == nil || out[1].Del.DN != "uid=gone,dc=example,dc=org" {
+		t.Errorf("wrong delete record: %#v", out[1])
+	}
+}
+
+func TestEntryJSONMarshaler(t *testing.T) {
+	var e ldif.Entry
+	err := json.Unmarshal([]byte(`{"dn":"cn=x,dc=example,dc=org","changetype":"delete"}`), &e)
+	if err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+	if e.Del == nil {
+		t.Fatalf("expected delete record, got %#v", e)
+	}
+	data, err := json.Marshal(&e)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	if string(data) != `{"dn":"cn=x,dc=example,dc=org","changetype":"delete"}` {
+		t.Errorf("wrong JSON: %s", data)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	for name, in := range map[string]string{
+		"bad changetype": `[{"dn":"cn=x","changetype":"foo"}]`,
+		"bad operation":  `[{"dn":"cn=x","changetype":"modify","changes":[{"operation":"foo","name":"cn","values":[]}]}]`,
+		"bad base64":     `[{"dn":"cn=x","attributes":[{"name":"cn","values":["%%%"],"base64":true}]}]`,
+		"bad oid":        `[{"dn":"cn=x","changetype":"delete","controls":[{"oid":"1..2"}]}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ldif.FromJSON([]byte(in))
+			if err == nil {
+				t.Error("expected error")
+			}
+			if !strings.HasPrefix(err.Error(), "record 0:") {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
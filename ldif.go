@@ -10,7 +10,7 @@ import (
 	"io"
 	"iter"
 	"net/url"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -19,29 +19,108 @@ import (
 
 // Entry is one entry in the LDIF
 type Entry struct {
-	Entry  *ldap.Entry
-	Add    *ldap.AddRequest
-	Del    *ldap.DelRequest
-	Modify *ldap.ModifyRequest
+	Entry    *ldap.Entry
+	Add      *ldap.AddRequest
+	Del      *ldap.DelRequest
+	Modify   *ldap.ModifyRequest
+	ModifyDN *ldap.ModifyDNRequest
+}
+
+// DN returns the DN of the record, or an empty string for an empty Entry.
+func (e *Entry) DN() string {
+	switch {
+	case e.Entry != nil:
+		return e.Entry.DN
+	case e.Add != nil:
+		return e.Add.DN
+	case e.Del != nil:
+		return e.Del.DN
+	case e.Modify != nil:
+		return e.Modify.DN
+	case e.ModifyDN != nil:
+		return e.ModifyDN.DN
+	}
+	return ""
 }
 
 // The LDIF struct is used for parsing an LDIF. The Controls
 // is used to tell the parser to ignore any controls found
 // when parsing (default: false to ignore the controls).
 // FoldWidth is used for the line lenght when marshalling.
+// Mode selects strict or lenient parsing, see Mode for the differences.
+// Limits restrict the resources used when parsing untrusted input.
 type LDIF struct {
-	Entries    []*Entry
-	Version    int
-	FoldWidth  int
-	Controls   bool
-	firstEntry bool
+	Entries   []*Entry
+	Version   int
+	FoldWidth int
+	Controls  bool
+	Mode      Mode
+	Limits    Limits
+	// Operational is applied to the records by Apply before they are
+	// sent, see HandleOperational.
+	Operational OperationalPolicy
+	// Profile selects the conventions of a directory server's LDIF tool
+	// for the parser and Marshal, see Profile.
+	Profile Profile
+	// DSMLURLs makes UnmarshalDSML read the values of type xsd:anyURI
+	// from their URL. It is off, as a DSML document from an untrusted
+	// source could read any local file this way.
+	DSMLURLs bool
+	// OperationalLast makes Marshal write the operational attributes of
+	// content and add records after all other attributes, like slapcat.
+	OperationalLast bool
+	firstEntry      bool
+	// keepGoing continues with the next record after a parse error
+	keepGoing bool
+	// recordLine is the first line of the last yielded record
+	recordLine int
+}
+
+// Limits restrict the size of the input accepted by the parser. A limit
+// of 0 means no limit.
+type Limits struct {
+	// MaxLineLength is the maximum length of a physical line in bytes
+	MaxLineLength int
+	// MaxLogicalLineLength is the maximum length of an unfolded line
+	MaxLogicalLineLength int
+	// MaxValuesPerAttribute is the maximum number of values of one
+	// attribute in a record or one modification of a modify record
+	MaxValuesPerAttribute int
+	// MaxAttributesPerEntry is the maximum number of attributes of a
+	// record or modifications of a modify record
+	MaxAttributesPerEntry int
+	// MaxEntrySize is the maximum size of the unfolded lines of a record,
+	// it also limits the size of a value read from a file URL (after
+	// decompression)
+	MaxEntrySize int
+	// MaxEntries is the maximum number of records
+	MaxEntries int
+}
+
+// LimitError is the cause of a ParseError when the input exceeds one of
+// the Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded limit, e.g. "MaxLineLength"
+	Limit string
+	// Max is the configured value of the limit
+	Max int
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit %s of %d exceeded", e.Limit, e.Max)
 }
 
 // The ParseError holds the error message and the line in the ldif
-// where the error occurred.
+// where the error occurred. Err is the underlying error, use errors.Is
+// with one of the Err* kinds to inspect it. Attribute and DN are set if
+// they are known.
 type ParseError struct {
-	Line    int
-	Message string
+	Line      int
+	Message   string
+	Err       error
+	Attribute string
+	DN        string
 }
 
 // Error implements the error interface
@@ -49,6 +128,11 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("Error in line %d: %s", e.Line, e.Message)
 }
 
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 var cr byte = '\x0D'
 var lf byte = '\x0A'
 var sep = string([]byte{cr, lf})
@@ -106,54 +190,130 @@ func UnmarshalEntries(r io.Reader, l *LDIF) iter.Seq2[*Entry, error] {
 }
 
 func unmarshalEntries(r io.Reader, l *LDIF) iter.Seq2[*Entry, error] {
+	return unmarshalRecords(r, l, true)
+}
+
+// unmarshalRecords parses the records from r, a version line is only
+// accepted if first is true, i.e. r is at the start of the LDIF.
+//
+// The (unfolded) lines of a record are collected in one reusable buffer,
+// which is converted to a single string per record. The lines passed to
+// parseEntry and all values which are not base64 encoded are substrings
+// of that string.
+func unmarshalRecords(r io.Reader, l *LDIF, first bool) iter.Seq2[*Entry, error] {
 	return func(yield func(*Entry, error) bool) {
 		if r == nil {
-			yield(nil, &ParseError{Line: 0, Message: "No reader present"})
+			yield(nil, &ParseError{Line: 0, Message: "No reader present", Err: ErrNoReader})
 			return
 		}
 
 		curLine := 0
 		l.Version = 0
 		isComment := false
+		mode := l.Mode
 
-		reader := bufio.NewReader(r)
-
-		var lines []string
-		var line, nextLine string
-		l.firstEntry = true
+		if l.Profile == ProfileActiveDirectory && first {
+			r = utf16Reader(r)
+		}
+		if mode == ModeLenient {
+			r = &crReader{r: r}
+		}
+		br := bufio.NewReader(r)
+		if mode == ModeLenient && first {
+			if head, _ := br.Peek(len(bom)); bytes.Equal(head, bom) {
+				br.Discard(len(bom))
+			}
+		}
+		reader := &lineReader{r: br, max: l.Limits.MaxLineLength}
+		limits := l.Limits
+		entries := 0
+		changes := 0     // number of change records, for the ModeStrict mixing check
+		pending := false // ModeLenient: blank line inside a modify record
+
+		var (
+			buf         []byte   // the unfolded lines of the current record
+			ends        []int    // end offsets of the completed lines in buf
+			lineStart   int      // start offset of the current line in buf
+			lines       []string // reused for the lines passed to parseEntry
+			recordStart int      // the first line of the current record
+		)
+		l.firstEntry = first
+
+		// finish parses the collected record and yields it, it returns
+		// false if the iteration must stop.
+		finish := func() bool {
+			pending = false
+			ends = append(ends, len(buf))
+			record := string(buf)
+			lines = lines[:0]
+			start := 0
+			for _, end := range ends {
+				lines = append(lines, record[start:end])
+				start = end
+			}
+			buf = buf[:0]
+			ends = ends[:0]
+			lineStart = 0
+
+			l.recordLine = recordStart
+			entry, perr := l.parseEntry(lines)
+			l.firstEntry = false
+			if perr != nil {
+				return yield(nil, newParseError(curLine, perr)) && l.keepGoing
+			}
+			if entry != nil {
+				entries++
+				if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+					yield(nil, newParseError(curLine, withDN(&LimitError{Limit: "MaxEntries", Max: limits.MaxEntries}, entry.DN())))
+					return false
+				}
+				if entry.Entry == nil {
+					changes++
+				}
+				if mode == ModeStrict && changes != 0 && changes != entries {
+					return yield(nil, newParseError(curLine, withDN(errorf(ErrMixed, "%s", ErrMixed), entry.DN()))) && l.keepGoing
+				}
+			}
+			return yield(entry, nil)
+		}
 
 		for {
 			curLine++
-			var err error
-			nextLine, err = reader.ReadString(lf)
-			nextLine = strings.TrimRight(nextLine, sep)
+			nextLine, err := reader.next()
 
 			switch err {
 			case nil, io.EOF:
 				switch len(nextLine) {
 				case 0:
-					if len(line) == 0 && err == io.EOF {
+					if len(buf) == lineStart && err == io.EOF {
 						return
 					}
-					if len(line) == 0 && len(lines) == 0 {
+					if len(buf) == 0 && len(ends) == 0 {
 						continue
 					}
-					lines = append(lines, line)
-					entry, perr := l.parseEntry(lines)
-					if perr != nil {
-						yield(nil, &ParseError{Line: curLine, Message: perr.Error()})
-						return
-					}
-					if !yield(entry, nil) {
-						return
+					if mode == ModeLenient && err == nil && isModifyRecord(buf, ends) {
+						// the record ends at the next "dn:" line
+						pending = true
+						continue
 					}
-					line = ""
-					lines = []string{}
-					if err == io.EOF {
+					if !finish() || err == io.EOF {
 						return
 					}
 				default:
-					switch nextLine[0] {
+					if pending && nextLine[0] != comment {
+						pending = false
+						if bytes.HasPrefix(nextLine, dnPrefix) && !finish() {
+							return
+						}
+					}
+					if len(buf) == 0 && len(ends) == 0 {
+						recordStart = curLine
+					}
+					c := nextLine[0]
+					if c == tab && mode == ModeLenient {
+						c = space
+					}
+					switch c {
 					case comment:
 						isComment = true
 						continue
@@ -162,40 +322,85 @@ func unmarshalEntries(r io.Reader, l *LDIF) iter.Seq2[*Entry, error] {
 						if isComment {
 							continue
 						}
-						line += nextLine[1:]
-						continue
+						buf = append(buf, nextLine[1:]...)
 
 					default:
 						isComment = false
-						if len(line) != 0 {
-							lines = append(lines, line)
+						if len(buf) != lineStart {
+							ends = append(ends, len(buf))
+							lineStart = len(buf)
 						}
-						line = nextLine
-						continue
+						buf = append(buf, nextLine...)
 					}
+					var lerr *LimitError
+					switch {
+					case limits.MaxLogicalLineLength > 0 && len(buf)-lineStart > limits.MaxLogicalLineLength:
+						lerr = &LimitError{Limit: "MaxLogicalLineLength", Max: limits.MaxLogicalLineLength}
+					case limits.MaxEntrySize > 0 && len(buf) > limits.MaxEntrySize:
+						lerr = &LimitError{Limit: "MaxEntrySize", Max: limits.MaxEntrySize}
+					}
+					if lerr != nil {
+						yield(nil, newParseError(curLine, lerr))
+						return
+					}
+					continue
 				}
 			default:
-				yield(nil, &ParseError{Line: curLine, Message: err.Error()})
+				yield(nil, newParseError(curLine, err))
 				return
 			}
 		}
 	}
 }
 
+// lineReader reads the physical lines of an LDIF without copying them,
+// unless they are longer than the buffer of the bufio.Reader. Lines longer
+// than max (if > 0) are not buffered, a *LimitError is returned instead.
+type lineReader struct {
+	r    *bufio.Reader
+	long []byte
+	max  int
+}
+
+// next returns the next line without the line break. The returned slice is
+// only valid until the next call.
+func (lr *lineReader) next() ([]byte, error) {
+	line, err := lr.r.ReadSlice(lf)
+	if err == bufio.ErrBufferFull {
+		lr.long = append(lr.long[:0], line...)
+		for err == bufio.ErrBufferFull {
+			if lr.max > 0 && len(lr.long) > lr.max+len(sep) {
+				return nil, &LimitError{Limit: "MaxLineLength", Max: lr.max}
+			}
+			line, err = lr.r.ReadSlice(lf)
+			lr.long = append(lr.long, line...)
+		}
+		line = lr.long
+	}
+	line = bytes.TrimRight(line, sep)
+	if lr.max > 0 && len(line) > lr.max {
+		return nil, &LimitError{Limit: "MaxLineLength", Max: lr.max}
+	}
+	return line, err
+}
+
 func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 	if len(lines) == 0 {
-		return nil, errors.New("empty entry?")
+		return nil, errorf(ErrEmptyRecord, "empty entry?")
 	}
 
+	if l.firstEntry && l.Mode == ModeStrict && !strings.HasPrefix(lines[0], "version:") {
+		return nil, errorf(ErrInvalidVersion, "missing version line")
+	}
 	if l.firstEntry && strings.HasPrefix(lines[0], "version:") {
 		l.firstEntry = false
 		line := strings.TrimLeft(lines[0][8:], spaces)
 		if l.Version, err = strconv.Atoi(line); err != nil {
-			return nil, err
+			return nil, wrapError(ErrInvalidVersion, err)
 		}
 
 		if l.Version != 1 {
-			return nil, errors.New("Invalid version spec " + string(line))
+			return nil, errorf(ErrInvalidVersion, "Invalid version spec %s", line)
 		}
 
 		l.Version = 1
@@ -211,16 +416,21 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 	}
 
 	if !strings.HasPrefix(lines[0], "dn:") {
-		return nil, errors.New("missing 'dn:'")
+		return nil, errorf(ErrMissingDN, "missing 'dn:'")
 	}
 	_, val, err := l.parseLine(lines[0])
 	if err != nil {
 		return nil, err
 	}
 	dn := val
+	defer func() {
+		if err != nil {
+			err = withDN(err, dn)
+		}
+	}()
 
 	if len(lines) == 1 {
-		return nil, errors.New("only a dn: line")
+		return nil, errorf(ErrEmptyRecord, "only a dn: line")
 	}
 	lines = lines[1:]
 
@@ -231,12 +441,14 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 	}
 
 	var changeType string
+	var attrLines []string // the lines after the changetype line
 	if strings.HasPrefix(lines[0], "changetype:") {
 		_, val, err := l.parseLine(lines[0])
 		if err != nil {
 			return nil, err
 		}
 		changeType = val
+		attrLines = lines[1:]
 		if len(lines) > 1 {
 			lines = lines[1:]
 		}
@@ -244,7 +456,7 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 	switch changeType {
 	case "":
 		if len(controls) != 0 {
-			return nil, errors.New("controls found without changetype")
+			return nil, errorf(ErrInvalidControl, "controls found without changetype")
 		}
 		attrs, err := l.parseAttrs(lines)
 		if err != nil {
@@ -265,8 +477,8 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 		return &Entry{Add: add}, nil
 
 	case "delete":
-		if len(lines) > 1 {
-			return nil, errors.New("no attributes allowed for changetype delete")
+		if len(lines) > 1 || (l.Mode == ModeStrict && len(attrLines) != 0) {
+			return nil, errorf(ErrInvalidChangeRecord, "no attributes allowed for changetype delete")
 		}
 		return &Entry{Del: ldap.NewDelRequest(dn, controls)}, nil
 
@@ -275,15 +487,25 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 		mod := ldap.NewModifyRequest(dn, controls)
 		var op, attribute string
 		var values []string
+		var mods int
 		if lines[len(lines)-1] != "-" {
-			return nil, errors.New("modify request does not close with a single dash")
+			if l.Mode != ModeLenient {
+				return nil, errorf(ErrUnterminatedModify, "modify request does not close with a single dash")
+			}
+			lines = append(lines, "-")
 		}
 
 		for i := 0; i < len(lines); i++ {
+			if l.Mode == ModeLenient && op != "" && lines[i] != "-" {
+				// a missing "-" before the next modification
+				if attr, _, _ := strings.Cut(lines[i], ":"); attr != attribute && isModifyOp(attr) {
+					lines = slices.Insert(lines, i, "-")
+				}
+			}
 			if lines[i] == "-" {
 				switch op {
 				case "":
-					return nil, fmt.Errorf("empty operation")
+					return nil, errorf(ErrInvalidModifyOperation, "empty operation")
 				case "add":
 					mod.Add(attribute, values)
 					op = ""
@@ -299,8 +521,17 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 					op = ""
 					attribute = ""
 					values = nil
+				case "increment":
+					// RFC 4525
+					if len(values) != 1 {
+						return nil, withAttr(errorf(ErrInvalidModifyOperation, "increment of %s requires exactly one value", attribute), attribute)
+					}
+					mod.Increment(attribute, values[0])
+					op = ""
+					attribute = ""
+					values = nil
 				default:
-					return nil, fmt.Errorf("invalid operation %s in modify request", op)
+					return nil, withAttr(errorf(ErrInvalidModifyOperation, "invalid operation %s in modify request", op), attribute)
 				}
 				continue
 			}
@@ -311,20 +542,51 @@ func (l *LDIF) parseEntry(lines []string) (entry *Entry, err error) {
 			if op == "" {
 				op = attr
 				attribute = val
+				mods++
 			} else {
 				if attr != attribute {
-					return nil, fmt.Errorf("invalid attribute %s in %s request for %s", attr, op, attribute)
+					return nil, withAttr(errorf(ErrInvalidModifyOperation, "invalid attribute %s in %s request for %s", attr, op, attribute), attr)
 				}
 				values = append(values, val)
 			}
+			if err := l.checkAttrLimits(mods, len(values)); err != nil {
+				return nil, withAttr(err, attribute)
+			}
 		}
 		return &Entry{Modify: mod}, nil
 
 	case "moddn", "modrdn":
-		return nil, fmt.Errorf("unsupported changetype %s", changeType)
+		// ldap.ModifyDNRequest has no controls
+		if len(controls) != 0 {
+			return nil, errorf(ErrUnsupportedControl, "controls are not supported for changetype %s", changeType)
+		}
+		if len(lines) < 2 || len(lines) > 3 {
+			return nil, errorf(ErrInvalidChangeRecord, "changetype %s requires newrdn, deleteoldrdn and an optional newsuperior", changeType)
+		}
+		var newRDN, deleteOldRDN, newSuperior string
+		for i, line := range lines {
+			attr, val, err := l.parseLine(line)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case i == 0 && strings.EqualFold(attr, "newrdn"):
+				newRDN = val
+			case i == 1 && strings.EqualFold(attr, "deleteoldrdn"):
+				deleteOldRDN = val
+			case i == 2 && strings.EqualFold(attr, "newsuperior"):
+				newSuperior = val
+			default:
+				return nil, withAttr(errorf(ErrInvalidChangeRecord, "invalid attribute %s in %s request", attr, changeType), attr)
+			}
+		}
+		if deleteOldRDN != "0" && deleteOldRDN != "1" {
+			return nil, withAttr(errorf(ErrInvalidChangeRecord, "invalid deleteoldrdn value %q", deleteOldRDN), "deleteoldrdn")
+		}
+		return &Entry{ModifyDN: ldap.NewModifyDNRequest(dn, newRDN, deleteOldRDN == "1", newSuperior)}, nil
 
 	default:
-		return nil, fmt.Errorf("invalid changetype %s", changeType)
+		return nil, withAttr(errorf(ErrInvalidChangeType, "invalid changetype %s", changeType), "changetype")
 	}
 }
 
@@ -336,26 +598,42 @@ func (l *LDIF) parseAttrs(lines []string) (map[string][]string, error) {
 			return nil, err
 		}
 		attrs[attr] = append(attrs[attr], val)
+		if err := l.checkAttrLimits(len(attrs), len(attrs[attr])); err != nil {
+			return nil, withAttr(err, attr)
+		}
 	}
 	return attrs, nil
 }
 
+// checkAttrLimits checks the number of attributes of a record and the
+// number of values of an attribute against the Limits.
+func (l *LDIF) checkAttrLimits(attrs, values int) error {
+	if max := l.Limits.MaxAttributesPerEntry; max > 0 && attrs > max {
+		return &LimitError{Limit: "MaxAttributesPerEntry", Max: max}
+	}
+	if max := l.Limits.MaxValuesPerAttribute; max > 0 && values > max {
+		return &LimitError{Limit: "MaxValuesPerAttribute", Max: max}
+	}
+	return nil
+}
+
 func (l *LDIF) parseLine(line string) (attr, val string, err error) {
 	off := 0
 	for len(line) > off && line[off] != ':' {
 		off++
 		if off >= len(line) {
-			err = fmt.Errorf("Missing : in line `%s`", line)
+			err = errorf(ErrInvalidLine, "Missing : in line `%s`", line)
 			return
 		}
 	}
 	if off == len(line) {
-		err = fmt.Errorf("Missing : in the line `%s`", line)
+		err = errorf(ErrInvalidLine, "Missing : in the line `%s`", line)
 		return
 	}
 
 	attr = line[0:off]
 	if err = validAttr(attr); err != nil {
+		err = withAttr(errorf(ErrInvalidAttributeName, "%s", err), attr)
 		attr = ""
 		val = ""
 		return
@@ -372,17 +650,25 @@ func (l *LDIF) parseLine(line string) (attr, val string, err error) {
 	case ':':
 		val, err = decodeBase64(strings.TrimLeft(line[off+2:], spaces))
 		if err != nil {
+			err = withAttr(wrapError(ErrInvalidBase64, err), attr)
 			return
 		}
 
 	case '<':
-		val, err = readURLValue(strings.TrimLeft(line[off+2:], spaces))
+		val, err = readURLValue(strings.TrimLeft(line[off+2:], spaces), l.Limits.MaxEntrySize)
 		if err != nil {
+			err = withAttr(err, attr)
 			return
 		}
 
 	default:
 		val = strings.TrimLeft(line[off+1:], spaces)
+		if l.Mode == ModeStrict {
+			if err = validSafeString(val); err != nil {
+				err = withAttr(wrapError(ErrInvalidValue, err), attr)
+				return
+			}
+		}
 	}
 
 	return
@@ -396,7 +682,7 @@ func (l *LDIF) parseControls(lines []string) ([]ldap.Control, []string, error) {
 		}
 		if !l.Controls {
 			if len(lines) == 1 {
-				return nil, nil, errors.New("only controls found")
+				return nil, nil, errorf(ErrInvalidControl, "only controls found")
 			}
 			lines = lines[1:]
 			continue
@@ -412,7 +698,7 @@ func (l *LDIF) parseControls(lines []string) ([]ldap.Control, []string, error) {
 
 		parts := strings.SplitN(val, " ", 3)
 		if err = validOID(parts[0]); err != nil {
-			return nil, nil, fmt.Errorf("%s is not a valid oid: %s", oid, err)
+			return nil, nil, errorf(ErrInvalidControl, "%s is not a valid oid: %s", parts[0], err)
 		}
 		oid = parts[0]
 
@@ -437,32 +723,32 @@ func (l *LDIF) parseControls(lines []string) ([]ldap.Control, []string, error) {
 			case ldap.ControlTypeManageDsaIT:
 				controls = append(controls, &ldap.ControlManageDsaIT{Criticality: criticality})
 			default:
-				return nil, nil, fmt.Errorf("unsupported control found: %s", oid)
+				return nil, nil, errorf(ErrUnsupportedControl, "unsupported control found: %s", oid)
 			}
 		} else {
 			switch ctrlValue[0] { // where is this documented?
 			case ':':
 				if len(ctrlValue) == 1 {
-					return nil, nil, errors.New("missing value for base64 encoded control value")
+					return nil, nil, errorf(ErrInvalidControl, "missing value for base64 encoded control value")
 				}
 				ctrlValue, err = decodeBase64(strings.TrimLeft(ctrlValue[1:], spaces))
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, wrapError(ErrInvalidBase64, err)
 				}
 				if ctrlValue == "" {
-					return nil, nil, errors.New("base64 decoded to empty value")
+					return nil, nil, errorf(ErrInvalidControl, "base64 decoded to empty value")
 				}
 
 			case '<':
 				if len(ctrlValue) == 1 {
-					return nil, nil, errors.New("missing value for url control value")
+					return nil, nil, errorf(ErrInvalidControl, "missing value for url control value")
 				}
-				ctrlValue, err = readURLValue(strings.TrimLeft(ctrlValue[1:], spaces))
+				ctrlValue, err = readURLValue(strings.TrimLeft(ctrlValue[1:], spaces), l.Limits.MaxEntrySize)
 				if err != nil {
 					return nil, nil, err
 				}
 				if ctrlValue == "" {
-					return nil, nil, errors.New("url resolved to an empty value")
+					return nil, nil, errorf(ErrInvalidControl, "url resolved to an empty value")
 				}
 			}
 			// TODO:
@@ -476,35 +762,47 @@ func (l *LDIF) parseControls(lines []string) ([]ldap.Control, []string, error) {
 			// that should be usable in github.com/go-ldap/ldap/control.go also
 			// to decode the incoming control
 			// controls = append(controls, ctrl)
-			return nil, nil, fmt.Errorf("controls with values are not supported, oid: %s", oid)
+			return nil, nil, errorf(ErrUnsupportedControl, "controls with values are not supported, oid: %s", oid)
 		}
 
 		if len(lines) == 1 {
-			return nil, nil, errors.New("only controls found")
+			return nil, nil, errorf(ErrInvalidControl, "only controls found")
 		}
 		lines = lines[1:]
 	}
 	return controls, lines, nil
 }
 
-func readURLValue(val string) (string, error) {
+// readURLValue reads the value from the file of the URL, a file larger than
+// max bytes (if max > 0) is a *LimitError.
+func readURLValue(val string, max int) (string, error) {
 	u, err := url.Parse(val)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse URL: %s", err)
+		return "", &recordError{kind: ErrInvalidURL, cause: err, msg: fmt.Sprintf("failed to parse URL: %s", err)}
 	}
 	if u.Scheme != "file" {
-		return "", fmt.Errorf("unsupported URL scheme %s", u.Scheme)
+		return "", errorf(ErrUnsupportedURLScheme, "unsupported URL scheme %s", u.Scheme)
+	}
+	data, err := readFile(toPath(u), max)
+	var lerr *LimitError
+	if errors.As(err, &lerr) {
+		return "", lerr
 	}
-	data, err := os.ReadFile(toPath(u))
 	if err != nil {
-		return "", fmt.Errorf("failed to read %s: %s", u.Path, err)
+		return "", &recordError{cause: err, msg: fmt.Sprintf("failed to read %s: %s", u.Path, err)}
 	}
 	val = string(data) // FIXME: safe?
 	return val, nil
 }
 
+// decodeBase64 decodes the value. The encoded string is copied to a byte
+// slice, decoded into a buffer and the decoded bytes are copied to the
+// returned string, so it shares no memory with the input.
 func decodeBase64(enc string) (string, error) {
-	dec := make([]byte, base64.StdEncoding.DecodedLen(len([]byte(enc))))
+	if enc == "" {
+		return "", nil
+	}
+	dec := make([]byte, base64.StdEncoding.DecodedLen(len(enc)))
 	n, err := base64.StdEncoding.Decode(dec, []byte(enc))
 	if err != nil {
 		return "", err
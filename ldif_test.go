@@ -1,7 +1,9 @@
 package ldif_test
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -524,3 +526,68 @@ func countChangeType(typ string, ch []ldap.Change) []ldap.Change {
 	}
 	return c
 }
+
+func nChanges(n int) string {
+	var builder strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&builder, ""+
+			"dn: uid=someone%d,ou=people,dc=example,dc=org\n"+
+			"changetype: modify\n"+
+			"replace: mail\n"+
+			"mail: someone%d@example.org\n"+
+			"-\n"+
+			"add: description\n"+
+			"description: changed\n"+
+			"-\n"+
+			"\n",
+			i, i)
+	}
+	return builder.String()
+}
+
+func nLarge(n, size int, binary bool) string {
+	value := strings.Repeat("0123456789abcdef", size/16)
+	if binary {
+		value = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	l := &ldif.LDIF{}
+	for i := 0; i < n; i++ {
+		e := ldap.NewEntry(fmt.Sprintf("uid=someone%d,ou=people,dc=example,dc=org", i), map[string][]string{
+			"objectClass": {"top", "person"},
+			"uid":         {fmt.Sprintf("someone%d", i)},
+			"description": {value},
+		})
+		l.Entries = append(l.Entries, &ldif.Entry{Entry: e})
+	}
+	data, _ := ldif.Marshal(l)
+	if binary {
+		data = strings.ReplaceAll(data, "description: ", "description:: ")
+	}
+	return data
+}
+
+func BenchmarkUnmarshalEntries(b *testing.B) {
+	benchmarker := func(name string, data string) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				for _, err := range ldif.UnmarshalEntries(strings.NewReader(data), &ldif.LDIF{}) {
+					if err != nil {
+						b.Fatalf("Failed to unmarshal: %s", err)
+					}
+				}
+			}
+		})
+	}
+
+	for _, n := range []int{10, 1000} {
+		_, content := nPeople(n)
+		benchmarker(fmt.Sprintf("content %d", n), content)
+		benchmarker(fmt.Sprintf("changes %d", n), nChanges(n))
+	}
+	for _, size := range []int{1 << 10, 64 << 10} {
+		benchmarker(fmt.Sprintf("folded 100x%d", size), nLarge(100, size, false))
+		benchmarker(fmt.Sprintf("base64 100x%d", size), nLarge(100, size, true))
+	}
+}
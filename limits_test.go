@@ -0,0 +1,124 @@
+package ldif_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var limitsLDIF = `dn: uid=someone,dc=example,dc=org
+objectClass: top
+objectClass: person
+objectClass: organizationalPerson
+uid: someone
+cn: Someone
+description: a folded
+  description of someone
+
+dn: uid=other,dc=example,dc=org
+objectClass: top
+uid: other
+cn: Other
+
+dn: uid=someone,dc=example,dc=org
+changetype: modify
+add: mail
+mail: someone@example.org
+mail: someone@example.com
+-
+replace: cn
+cn: Some One
+-
+`
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits ldif.Limits
+		line   int
+	}{
+		{"MaxLineLength", ldif.Limits{MaxLineLength: 30}, 1},
+		{"MaxLogicalLineLength", ldif.Limits{MaxLogicalLineLength: 40}, 8},
+		{"MaxValuesPerAttribute", ldif.Limits{MaxValuesPerAttribute: 2}, 9},
+		{"MaxAttributesPerEntry", ldif.Limits{MaxAttributesPerEntry: 2}, 9},
+		{"MaxEntrySize", ldif.Limits{MaxEntrySize: 120}, 6},
+		{"MaxEntries", ldif.Limits{MaxEntries: 2}, 24},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ldif.Unmarshal(strings.NewReader(limitsLDIF), &ldif.LDIF{Limits: tc.limits})
+			var lerr *ldif.LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected LimitError, got %v", err)
+			}
+			if lerr.Limit != tc.name {
+				t.Errorf("got limit %s, expected %s", lerr.Limit, tc.name)
+			}
+			var perr *ldif.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected ParseError, got %v", err)
+			}
+			if perr.Line != tc.line {
+				t.Errorf("got error in line %d, expected %d", perr.Line, tc.line)
+			}
+		})
+	}
+
+	l := &ldif.LDIF{Limits: ldif.Limits{
+		MaxLineLength:         40,
+		MaxLogicalLineLength:  50,
+		MaxValuesPerAttribute: 3,
+		MaxAttributesPerEntry: 5,
+		MaxEntrySize:          250,
+		MaxEntries:            3,
+	}}
+	if err := ldif.Unmarshal(strings.NewReader(limitsLDIF), l); err != nil {
+		t.Fatalf("Unmarshal with limits: %s", err)
+	}
+	if len(l.Entries) != 3 {
+		t.Errorf("got %d entries, expected 3", len(l.Entries))
+	}
+}
+
+// endless is an endless line without a line break.
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func TestLimitsEndlessLine(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("dn: "), endless{})
+	err := ldif.Unmarshal(r, &ldif.LDIF{Limits: ldif.Limits{MaxLineLength: 1 << 20}})
+	var lerr *ldif.LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxLineLength" {
+		t.Fatalf("expected MaxLineLength LimitError, got %v", err)
+	}
+}
+
+func TestLimitsParallel(t *testing.T) {
+	_, data := nPeople(3000)
+	r := strings.NewReader(data)
+	l := &ldif.LDIF{Limits: ldif.Limits{MaxEntries: 2500}}
+	n := 0
+	var err error
+	for _, err = range ldif.UnmarshalEntriesParallel(r, r.Size(), l, 4) {
+		if err != nil {
+			break
+		}
+		n++
+	}
+	var lerr *ldif.LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxEntries" {
+		t.Fatalf("expected MaxEntries LimitError, got %v", err)
+	}
+	if n != 2500 {
+		t.Errorf("got %d entries before the error, expected 2500", n)
+	}
+}
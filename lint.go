@@ -0,0 +1,69 @@
+package ldif
+
+import (
+	"io"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Lint reads all records of the LDIF in r and returns all problems found,
+// unlike Unmarshal it does not stop at the first invalid record. The
+// records are parsed as configured in l (Mode, Controls and Limits), the
+// parsed records are not stored in l.
+//
+// Besides the parse errors, Lint reports invalid DNs (ErrInvalidDN) in the
+// dn, newrdn and newsuperior lines and content records with the DN of an
+// earlier content record (ErrDuplicateDN). These are reported with the
+// first line of the record.
+//
+// An I/O error or an exceeded MaxLineLength ends the check, it is returned
+// as the last problem.
+func Lint(r io.Reader, l *LDIF) []*ParseError {
+	var problems []*ParseError
+	seen := make(map[string]int)
+
+	report := func(err error) {
+		perr, ok := err.(*ParseError)
+		if !ok {
+			perr = newParseError(l.recordLine, err)
+		}
+		problems = append(problems, perr)
+	}
+
+	l.keepGoing = true
+	defer func() { l.keepGoing = false }()
+	for entry, err := range unmarshalEntries(r, l) {
+		if err != nil {
+			report(err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+
+		dn := entry.DN()
+		key, err := normalizeDN(dn)
+		if err != nil {
+			report(withDN(errorf(ErrInvalidDN, "invalid DN %q: %s", dn, err), dn))
+			continue
+		}
+		if entry.ModifyDN != nil {
+			if _, err := ldap.ParseDN(entry.ModifyDN.NewRDN); err != nil {
+				report(withAttr(withDN(errorf(ErrInvalidDN, "invalid newrdn %q: %s", entry.ModifyDN.NewRDN, err), dn), "newrdn"))
+			}
+			if sup := entry.ModifyDN.NewSuperior; sup != "" {
+				if _, err := ldap.ParseDN(sup); err != nil {
+					report(withAttr(withDN(errorf(ErrInvalidDN, "invalid newsuperior %q: %s", sup, err), dn), "newsuperior"))
+				}
+			}
+		}
+		if entry.Entry != nil {
+			if line, ok := seen[key]; ok {
+				report(withDN(errorf(ErrDuplicateDN, "duplicate DN %q, first seen in line %d", dn, line), dn))
+				continue
+			}
+			seen[key] = l.recordLine
+		}
+	}
+	return problems
+}
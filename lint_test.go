@@ -0,0 +1,58 @@
+package ldif_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var lintLDIF = `version: 1
+dn: cn=foo,dc=example,dc=org
+cn: foo
+
+dn: cn=bar,dc=example,dc=org
+cn bar
+
+# a duplicate
+dn: CN=Foo, dc=example,dc=org
+cn: foo
+
+dn: cn=baz,,dc=example
+cn: baz
+
+dn: cn=ok,dc=example,dc=org
+description:: !!!
+
+dn: cn=last,dc=example,dc=org
+cn: last
+`
+
+func TestLint(t *testing.T) {
+	problems := ldif.Lint(strings.NewReader(lintLDIF), &ldif.LDIF{})
+	expected := []struct {
+		line int
+		kind error
+	}{
+		{7, ldif.ErrInvalidLine},
+		{9, ldif.ErrDuplicateDN},
+		{12, ldif.ErrInvalidDN},
+		{17, ldif.ErrInvalidBase64},
+	}
+	if len(problems) != len(expected) {
+		for _, p := range problems {
+			t.Log(p)
+		}
+		t.Fatalf("got %d problems, expected %d", len(problems), len(expected))
+	}
+	for i, p := range problems {
+		if p.Line != expected[i].line || !errors.Is(p, expected[i].kind) {
+			t.Errorf("problem %d: got %q (line %d), expected %v in line %d", i, p.Message, p.Line, expected[i].kind, expected[i].line)
+		}
+	}
+
+	if problems := ldif.Lint(strings.NewReader(personLDIF), &ldif.LDIF{}); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
@@ -34,6 +34,10 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 	hasEntry := false
 	hasChange := false
 
+	if l.Profile == ProfileActiveDirectory {
+		writer = &crlfWriter{w: writer}
+	}
+
 	if l.Version > 0 {
 		_, err := io.WriteString(writer, "version: 1\n")
 		if err != nil {
@@ -47,6 +51,9 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 	}
 
 	for _, e := range l.Entries {
+		if l.Profile == ProfileActiveDirectory {
+			e = adRecord(e)
+		}
 		switch {
 		case e.Add != nil:
 			hasChange = true
@@ -54,7 +61,7 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 				return ErrMixed
 			}
 
-			_, err := io.WriteString(writer, foldLine("dn: "+e.Add.DN, fw)+"\n")
+			_, err := io.WriteString(writer, foldLine(dnLine(e.Add.DN), fw)+"\n")
 			if err != nil {
 				return err
 			}
@@ -64,7 +71,11 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 				return err
 			}
 
-			for _, add := range e.Add.Attributes {
+			attrs := e.Add.Attributes
+			if l.OperationalLast {
+				attrs = operationalLast(attrs, func(a ldap.Attribute) string { return a.Type })
+			}
+			for _, add := range attrs {
 				if len(add.Vals) == 0 {
 					return errors.New("changetype 'add' requires non empty value list")
 				}
@@ -88,7 +99,7 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 				return ErrMixed
 			}
 
-			_, err = io.WriteString(writer, foldLine("dn: "+e.Del.DN, fw)+"\n")
+			_, err = io.WriteString(writer, foldLine(dnLine(e.Del.DN), fw)+"\n")
 			if err != nil {
 				return err
 			}
@@ -104,7 +115,7 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 				return ErrMixed
 			}
 
-			_, err = io.WriteString(writer, foldLine("dn: "+e.Modify.DN, fw)+"\n")
+			_, err = io.WriteString(writer, foldLine(dnLine(e.Modify.DN), fw)+"\n")
 			if err != nil {
 				return err
 			}
@@ -166,10 +177,9 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 						return err
 					}
 				// replace operation - https://tools.ietf.org/html/rfc4511#section-4.6
+				// a replace without values deletes the attribute (RFC 2849
+				// example 6)
 				case 2:
-					if len(mod.Modification.Vals) == 0 {
-						return errors.New("changetype 'modify', op 'replace' requires non empty value list")
-					}
 					_, err = io.WriteString(writer, "replace: "+mod.Modification.Type+"\n")
 					if err != nil {
 						return err
@@ -190,22 +200,88 @@ func MarshalStreaming(l *LDIF, writer io.Writer) (err error) {
 					if err != nil {
 						return err
 					}
+				// increment operation - https://tools.ietf.org/html/rfc4525
+				case 3:
+					if len(mod.Modification.Vals) != 1 {
+						return errors.New("changetype 'modify', op 'increment' requires exactly one value")
+					}
+					ev, t := encodeValue(mod.Modification.Vals[0])
+					col := ": "
+					if t {
+						col = ":: "
+					}
+					_, err = io.WriteString(writer, "increment: "+mod.Modification.Type+"\n"+
+						foldLine(mod.Modification.Type+col+ev, fw)+"\n-\n")
+					if err != nil {
+						return err
+					}
 				default:
 					return fmt.Errorf("invalid type %s in modify request", mod.Modification.Type)
 				}
 			}
+		case e.ModifyDN != nil:
+			hasChange = true
+			if hasEntry {
+				return ErrMixed
+			}
+
+			_, err = io.WriteString(writer, foldLine(dnLine(e.ModifyDN.DN), fw)+"\n")
+			if err != nil {
+				return err
+			}
+
+			_, err = io.WriteString(writer, "changetype: modrdn\n")
+			if err != nil {
+				return err
+			}
+
+			ev, t := encodeValue(e.ModifyDN.NewRDN)
+			col := ": "
+			if t {
+				col = ":: "
+			}
+			_, err = io.WriteString(writer, foldLine("newrdn"+col+ev, fw)+"\n")
+			if err != nil {
+				return err
+			}
+
+			deleteOldRDN := "0"
+			if e.ModifyDN.DeleteOldRDN {
+				deleteOldRDN = "1"
+			}
+			_, err = io.WriteString(writer, "deleteoldrdn: "+deleteOldRDN+"\n")
+			if err != nil {
+				return err
+			}
+
+			if e.ModifyDN.NewSuperior != "" {
+				ev, t := encodeValue(e.ModifyDN.NewSuperior)
+				col := ": "
+				if t {
+					col = ":: "
+				}
+				_, err = io.WriteString(writer, foldLine("newsuperior"+col+ev, fw)+"\n")
+				if err != nil {
+					return err
+				}
+			}
+
 		default:
 			hasEntry = true
 			if hasChange {
 				return ErrMixed
 			}
 
-			_, err = io.WriteString(writer, foldLine("dn: "+e.Entry.DN, fw)+"\n")
+			_, err = io.WriteString(writer, foldLine(dnLine(e.Entry.DN), fw)+"\n")
 			if err != nil {
 				return err
 			}
 
-			for _, av := range e.Entry.Attributes {
+			attrs := e.Entry.Attributes
+			if l.OperationalLast {
+				attrs = operationalLast(attrs, func(a *ldap.EntryAttribute) string { return a.Name })
+			}
+			for _, av := range attrs {
 				for _, v := range av.Values {
 					ev, t := encodeValue(v)
 					col := ": "
@@ -258,32 +334,49 @@ func encodeValue(value string) (string, bool) {
 	return base64.StdEncoding.EncodeToString([]byte(value)), true
 }
 
+// dnLine returns the dn line for the DN, base64 encoded if required.
+func dnLine(dn string) string {
+	if ev, t := encodeValue(dn); t {
+		return "dn:: " + ev
+	}
+	return "dn: " + dn
+}
+
 func foldLine(line string, fw int) (folded string) {
 	if fw < 0 {
 		return line
 	}
+	if fw < 2 {
+		// a continuation line needs at least one character after the space
+		fw = 2
+	}
 	if len(line) <= fw {
 		return line
 	}
 
-	folded = line[:fw] + "\n"
+	var b strings.Builder
+	b.Grow(len(line) + 2*(len(line)/(fw-1)+1))
+	b.WriteString(line[:fw])
 	line = line[fw:]
 
 	for len(line) > fw-1 {
-		folded += " " + line[:fw-1] + "\n"
+		b.WriteString("\n ")
+		b.WriteString(line[:fw-1])
 		line = line[fw-1:]
 	}
 
 	if len(line) > 0 {
-		folded += " " + line
+		b.WriteString("\n ")
+		b.WriteString(line)
 	}
-	return
+	return b.String()
 }
 
 // Dump writes the given entries to the io.Writer.
 //
 // The entries argument can be *ldap.Entry or a mix of *ldap.AddRequest,
-// *ldap.DelRequest and *ldap.ModifyRequest or slices of any of those.
+// *ldap.DelRequest, *ldap.ModifyRequest and *ldap.ModifyDNRequest or slices
+// of any of those.
 //
 // See Marshal() for the fw argument.
 func Dump(fh io.Writer, fw int, entries ...interface{}) error {
@@ -299,7 +392,8 @@ func Dump(fh io.Writer, fw int, entries ...interface{}) error {
 // ToLDIF puts the given arguments in an LDIF struct and returns it.
 //
 // The entries argument can be *ldap.Entry or a mix of *ldap.AddRequest,
-// *ldap.DelRequest and *ldap.ModifyRequest or slices of any of those.
+// *ldap.DelRequest, *ldap.ModifyRequest and *ldap.ModifyDNRequest or slices
+// of any of those.
 func ToLDIF(entries ...interface{}) (*LDIF, error) {
 	l := &LDIF{}
 	for _, e := range entries {
@@ -335,6 +429,13 @@ func ToLDIF(entries ...interface{}) (*LDIF, error) {
 		case *ldap.ModifyRequest:
 			l.Entries = append(l.Entries, &Entry{Modify: e.(*ldap.ModifyRequest)})
 
+		case []*ldap.ModifyDNRequest:
+			for _, en := range e.([]*ldap.ModifyDNRequest) {
+				l.Entries = append(l.Entries, &Entry{ModifyDN: en})
+			}
+		case *ldap.ModifyDNRequest:
+			l.Entries = append(l.Entries, &Entry{ModifyDN: e.(*ldap.ModifyDNRequest)})
+
 		default:
 			return nil, fmt.Errorf("unsupported type %T", e)
 		}
@@ -418,6 +418,114 @@ changetype: delete
 	}
 }
 
+func TestMarshalDNB64(t *testing.T) {
+	dnLDIF := `dn:: dWlkPWrDtnJnLG91PXBlb3BsZSxkYz1leGFtcGxlLGRjPW9yZw==
+changetype: delete
+
+`
+	del := ldap.NewDelRequest("uid=jörg,ou=people,dc=example,dc=org", nil)
+	l := &ldif.LDIF{
+		Entries: []*ldif.Entry{
+			{Del: del},
+		},
+	}
+	res, err := ldif.Marshal(l)
+	if err != nil {
+		t.Errorf("Failed to marshal entry: %s", err)
+	}
+	if res != dnLDIF {
+		t.Errorf("unexpected result: >>%s<<", res)
+	}
+}
+
+func TestMarshalFoldWidthMinimum(t *testing.T) {
+	// a fold width of 1 would not leave room for a character after the
+	// leading space of a continuation line, 2 is used instead
+	l := &ldif.LDIF{Entries: []*ldif.Entry{{Entry: entries[0]}}, FoldWidth: 1}
+	res, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %s", err)
+	}
+	if !strings.HasPrefix(res, "dn\n :\n  \n o\n u\n") {
+		t.Errorf("unexpected result: >>%s<<", res)
+	}
+	l2, err := ldif.Parse(res)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	if got := l2.Entries[0].Entry.DN; got != entries[0].DN {
+		t.Errorf("got DN %q, expected %q", got, entries[0].DN)
+	}
+}
+
+func TestMarshalModReplaceNoValues(t *testing.T) {
+	// a replace without values deletes the attribute (RFC 2849 example 6)
+	modLDIF := `dn: uid=someone,ou=people,dc=example,dc=org
+changetype: modify
+replace: description
+-
+
+`
+	mod := ldap.NewModifyRequest("uid=someone,ou=people,dc=example,dc=org", nil)
+	mod.Replace("description", nil)
+	l := &ldif.LDIF{
+		Entries: []*ldif.Entry{
+			{Modify: mod},
+		},
+	}
+	res, err := ldif.Marshal(l)
+	if err != nil {
+		t.Errorf("Failed to marshal entry: %s", err)
+	}
+	if res != modLDIF {
+		t.Errorf("unexpected result: >>%s<<", res)
+	}
+	l2, err := ldif.Parse(res)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	if c := l2.Entries[0].Modify.Changes; len(c) != 1 || c[0].Operation != ldap.ReplaceAttribute || len(c[0].Modification.Vals) != 0 {
+		t.Errorf("unexpected changes: %+v", c)
+	}
+}
+
+func TestMarshalModIncrement(t *testing.T) {
+	modLDIF := `dn: uid=someone,ou=people,dc=example,dc=org
+changetype: modify
+increment: uidNumber
+uidNumber: 1
+-
+
+`
+	mod := ldap.NewModifyRequest("uid=someone,ou=people,dc=example,dc=org", nil)
+	mod.Increment("uidNumber", "1")
+	l := &ldif.LDIF{
+		Entries: []*ldif.Entry{
+			{Modify: mod},
+		},
+	}
+	res, err := ldif.Marshal(l)
+	if err != nil {
+		t.Errorf("Failed to marshal entry: %s", err)
+	}
+	if res != modLDIF {
+		t.Errorf("unexpected result: >>%s<<", res)
+	}
+	l2, err := ldif.Parse(res)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	if c := l2.Entries[0].Modify.Changes; len(c) != 1 || c[0].Operation != ldap.IncrementAttribute ||
+		c[0].Modification.Type != "uidNumber" || len(c[0].Modification.Vals) != 1 || c[0].Modification.Vals[0] != "1" {
+		t.Errorf("unexpected changes: %+v", c)
+	}
+
+	mod.Changes[0].Modification.Vals = []string{"1", "2"}
+	if _, err := ldif.Marshal(l); err == nil {
+		t.Error("increment with two values: no error")
+	}
+}
+
 func TestDump(t *testing.T) {
 	delLDIF := `dn: uid=someone,ou=people,dc=example,dc=org
 changetype: delete
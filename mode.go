@@ -0,0 +1,133 @@
+package ldif
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Mode selects how strictly the parser follows RFC 2849.
+type Mode int
+
+// The parser modes.
+//
+// ModeDefault is the behaviour of earlier versions: the version line is
+// optional, content and change records may be mixed, plain values may
+// contain any character except a line break and a modify record must not
+// contain blank lines and must end with a "-" line.
+//
+// ModeStrict enforces the grammar of RFC 2849, in addition to the default
+// rules:
+//   - the LDIF must start with "version: 1"
+//   - plain values must be a SAFE-STRING, i.e. 7-bit ASCII without NUL, CR
+//     and LF, not starting with a space, ":" or "<", other values must be
+//     base64 encoded
+//   - content and change records must not be mixed (ErrMixed)
+//   - a delete record must not have any attributes
+//
+// ModeLenient accepts common deviations found in real-world exports, in
+// addition to the default rules:
+//   - a UTF-8 byte order mark at the start of the input
+//   - CR-only line endings
+//   - a tab instead of a space at the start of a continuation line
+//   - blank lines inside a modify record: a blank line only ends a modify
+//     record if the next (non-comment) line starts with "dn:"
+//   - a missing "-" after the last modification of a modify record or
+//     before the next "add:", "delete:", "replace:" or "increment:" line
+//
+// UnmarshalEntriesParallel splits the input at blank lines, so it does not
+// support blank lines inside a modify record.
+const (
+	ModeDefault Mode = iota
+	ModeStrict
+	ModeLenient
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeDefault:
+		return "default"
+	case ModeStrict:
+		return "strict"
+	case ModeLenient:
+		return "lenient"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+var (
+	bom           = []byte{0xef, 0xbb, 0xbf}
+	tab      byte = '\t'
+	dnPrefix      = []byte("dn:")
+)
+
+// crReader replaces CR-only line endings with LF, a CR LF is passed through
+// as LF.
+type crReader struct {
+	r  io.Reader
+	cr bool
+}
+
+func (c *crReader) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b == lf && c.cr {
+				c.cr = false
+				continue
+			}
+			c.cr = b == cr
+			if c.cr {
+				b = lf
+			}
+			p[j] = b
+			j++
+		}
+		if j > 0 || n == 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// isModifyRecord reports whether the unfolded lines in buf, which end at
+// the given offsets and at len(buf), are a modify record.
+func isModifyRecord(buf []byte, ends []int) bool {
+	start := 0
+	for i := 0; i <= len(ends); i++ {
+		end := len(buf)
+		if i < len(ends) {
+			end = ends[i]
+		}
+		line := buf[start:end]
+		if bytes.HasPrefix(line, []byte("changetype:")) {
+			return string(bytes.TrimLeft(line[len("changetype:"):], spaces)) == "modify"
+		}
+		start = end
+	}
+	return false
+}
+
+// isModifyOp reports whether attr is the operation of a modification.
+func isModifyOp(attr string) bool {
+	switch attr {
+	case "add", "delete", "replace", "increment":
+		return true
+	}
+	return false
+}
+
+// validSafeString checks that val is a SAFE-STRING as defined in RFC 2849.
+func validSafeString(val string) error {
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		switch {
+		case c == 0 || c == cr || c == lf || c > 0x7f:
+			return fmt.Errorf("invalid character %#02x at position %d, value must be base64 encoded", c, i)
+		case i == 0 && (c == space || c == ':' || c == '<'):
+			return fmt.Errorf("value must not start with %q, it must be base64 encoded", c)
+		}
+	}
+	return nil
+}
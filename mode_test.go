@@ -0,0 +1,123 @@
+package ldif_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+func parseMode(in string, mode ldif.Mode) (*ldif.LDIF, error) {
+	l := &ldif.LDIF{Mode: mode}
+	err := ldif.Unmarshal(strings.NewReader(in), l)
+	return l, err
+}
+
+func TestModeStrict(t *testing.T) {
+	valid := "version: 1\ndn: cn=foo,dc=example,dc=org\ncn: foo\ndescription:: w7xiZXI=\n\n" +
+		"dn: cn=bar,dc=example,dc=org\ncn: bar\n"
+	if _, err := parseMode(valid, ldif.ModeStrict); err != nil {
+		t.Fatalf("unexpected error for valid LDIF: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		ldif string
+		kind error
+	}{
+		{"missing version", "dn: cn=foo\ncn: foo\n", ldif.ErrInvalidVersion},
+		{"non-ASCII value", "version: 1\ndn: cn=foo\ndescription: über\n", ldif.ErrInvalidValue},
+		{"unsafe first character", "version: 1\ndn: cn=foo\ndescription: :foo\n", ldif.ErrInvalidValue},
+		{"non-ASCII dn", "version: 1\ndn: cn=föo\ncn: föo\n", ldif.ErrInvalidValue},
+		{"mixed records", "version: 1\ndn: cn=foo\ncn: foo\n\ndn: cn=bar\nchangetype: delete\n", ldif.ErrMixed},
+		{"attribute for delete", "version: 1\ndn: cn=foo\nchangetype: delete\ncn: foo\n", ldif.ErrInvalidChangeRecord},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseMode(tc.ldif, ldif.ModeDefault); err != nil {
+				t.Fatalf("unexpected error in default mode: %s", err)
+			}
+			_, err := parseMode(tc.ldif, ldif.ModeStrict)
+			if !errors.Is(err, tc.kind) {
+				t.Fatalf("expected %v, got %v", tc.kind, err)
+			}
+		})
+	}
+}
+
+var ldifLenientModify = "\xef\xbb\xbfversion: 1\r" +
+	"dn: cn=Paula Jensen,ou=Product Development,dc=airius,dc=com\r" +
+	"changetype: modify\r" +
+	"add: postaladdress\r" +
+	"postaladdress: 123 Anystreet $ Sunnyvale, CA $ 9\r" +
+	"\t4086\r" +
+	"\r" +
+	"delete: description\r" +
+	"-\r" +
+	"\r" +
+	"# the next modification\r" +
+	"replace: telephonenumber\r" +
+	"telephonenumber: +1 408 555 1234\r" +
+	"\r" +
+	"dn: cn=Ursula Hampster,ou=Alumni Association,ou=People,dc=airius,dc=com\r" +
+	"changetype: delete\r"
+
+func TestModeLenient(t *testing.T) {
+	if _, err := parseMode(ldifLenientModify, ldif.ModeDefault); err == nil {
+		t.Fatal("expected error in default mode")
+	}
+	l, err := parseMode(ldifLenientModify, ldif.ModeLenient)
+	if err != nil {
+		t.Fatalf("unexpected error in lenient mode: %s", err)
+	}
+	if l.Version != 1 {
+		t.Errorf("version not parsed after BOM: %d", l.Version)
+	}
+	if len(l.Entries) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(l.Entries))
+	}
+	mod := l.Entries[0].Modify
+	if mod == nil {
+		t.Fatal("first entry not a modify request")
+	}
+	expected := []ldap.Change{
+		{Operation: ldap.AddAttribute, Modification: ldap.PartialAttribute{Type: "postaladdress", Vals: []string{"123 Anystreet $ Sunnyvale, CA $ 94086"}}},
+		{Operation: ldap.DeleteAttribute, Modification: ldap.PartialAttribute{Type: "description"}},
+		{Operation: ldap.ReplaceAttribute, Modification: ldap.PartialAttribute{Type: "telephonenumber", Vals: []string{"+1 408 555 1234"}}},
+	}
+	if len(mod.Changes) != len(expected) {
+		t.Fatalf("got %d changes, expected %d: %#v", len(mod.Changes), len(expected), mod.Changes)
+	}
+	for i, c := range mod.Changes {
+		e := expected[i]
+		if c.Operation != e.Operation || c.Modification.Type != e.Modification.Type ||
+			strings.Join(c.Modification.Vals, "|") != strings.Join(e.Modification.Vals, "|") {
+			t.Errorf("change %d: got %#v, expected %#v", i, c, e)
+		}
+	}
+	if l.Entries[1].Del == nil {
+		t.Error("second entry not a delete request")
+	}
+}
+
+func TestModeLenientCRLF(t *testing.T) {
+	in := strings.ReplaceAll(personLDIF, "\n", "\r\n")
+	l, err := parseMode(in, ldif.ModeLenient)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	d, err := parseMode(personLDIF, ldif.ModeDefault)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(l.Entries) != len(d.Entries) {
+		t.Fatalf("got %d entries, expected %d", len(l.Entries), len(d.Entries))
+	}
+	for i := range l.Entries {
+		if l.Entries[i].Entry.DN != d.Entries[i].Entry.DN {
+			t.Errorf("entry %d: got %s, expected %s", i, l.Entries[i].Entry.DN, d.Entries[i].Entry.DN)
+		}
+	}
+}
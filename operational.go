@@ -0,0 +1,195 @@
+package ldif
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// operationalAttributes are the lower cased names of the operational
+// attributes of RFC 4512 and RFC 3045 and the ones added by OpenLDAP,
+// 389-ds, ApacheDS and Active Directory, as found in their exports. Only
+// attributes which the server maintains itself are listed, not the ones
+// which administrators set, like nsAccountLock, pwdAccountLockedTime,
+// pwdPolicySubentry or primaryGroupID, even if their schema marks them as
+// operational.
+var operationalAttributes = map[string]bool{}
+
+func init() {
+	for _, name := range []string{
+		// RFC 4512, RFC 3045, RFC 4530
+		"createTimestamp", "creatorsName", "modifyTimestamp", "modifiersName",
+		"structuralObjectClass", "governingStructureRule", "subschemaSubentry",
+		"entryUUID", "hasSubordinates", "vendorName", "vendorVersion",
+		// OpenLDAP
+		"entryCSN", "contextCSN", "entryDN", "pwdChangedTime", "pwdFailureTime",
+		"pwdHistory", "pwdGraceUseTime", "authTimestamp", "numSubordinates",
+		// 389-ds
+		"nsUniqueId", "parentid", "entryid", "passwordGraceUserTime",
+		"passwordExpirationTime", "passwordHistory", "passwordAllowChangeTime",
+		"passwordRetryCount", "retryCountResetTime", "accountUnlockTime",
+		"nsRole", "entryusn", "nscpEntryDN", "nsTombstoneCSN",
+		// ApacheDS
+		"entryParentId", "nbChildren", "nbSubordinates", "subordinateCount",
+		"accessControlSubentries", "collectiveAttributeSubentries",
+		// Active Directory
+		"objectGUID", "objectSid", "whenCreated", "whenChanged", "uSNCreated", "uSNChanged",
+		"dSCorePropagationData", "instanceType", "badPwdCount", "badPasswordTime",
+		"lastLogon", "lastLogoff", "lastLogonTimestamp", "logonCount", "sAMAccountType",
+		"isCriticalSystemObject", "replPropertyMetaData", "objectVersion",
+	} {
+		operationalAttributes[strings.ToLower(name)] = true
+	}
+}
+
+// IsOperational reports whether the attribute is a known operational
+// attribute, i.e. one maintained by the server. The name is compared
+// case-insensitively, attribute options like ";binary" are ignored.
+func IsOperational(name string) bool {
+	name, _, _ = strings.Cut(name, ";")
+	return operationalAttributes[strings.ToLower(name)]
+}
+
+// OperationalAttributes returns the (lower cased) names of the known
+// operational attributes, sorted.
+func OperationalAttributes() []string {
+	names := make([]string, 0, len(operationalAttributes))
+	for name := range operationalAttributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ControlTypeRelaxRules is the OID of the relax rules control
+// (draft-zeilenga-ldap-relax), which allows OpenLDAP clients to set
+// operational attributes like entryUUID or createTimestamp.
+const ControlTypeRelaxRules = "1.3.6.1.4.1.4203.666.5.12"
+
+// An OperationalPolicy says what happens to the operational attributes
+// (see IsOperational) of a record, e.g. of a slapcat or db2ldif export,
+// before it is sent to a server.
+type OperationalPolicy int
+
+const (
+	// OperationalKeep sends the records as they are. Servers reject the
+	// adds of entries with most operational attributes.
+	OperationalKeep OperationalPolicy = iota
+	// OperationalStrip removes the operational attributes from content
+	// and add records and their changes from modify records, modify
+	// records without other changes are dropped.
+	OperationalStrip
+	// OperationalRelax sends the content, add and modify records with
+	// operational attributes with a critical relax rules control, which
+	// lets the server (OpenLDAP with the relax rules enabled) store them.
+	OperationalRelax
+)
+
+func (p OperationalPolicy) String() string {
+	switch p {
+	case OperationalKeep:
+		return "keep"
+	case OperationalStrip:
+		return "strip"
+	case OperationalRelax:
+		return "relax"
+	}
+	return fmt.Sprintf("OperationalPolicy(%d)", int(p))
+}
+
+// HandleOperational returns a Transform which applies the policy to the
+// operational attributes of the records. The records are not changed in
+// place: changed records are copies, which share the attributes and values
+// with the original. With OperationalStrip, modify records which only
+// change operational attributes are dropped, i.e. nil is returned. With
+// OperationalRelax, content records with operational attributes are
+// converted to add records.
+func HandleOperational(policy OperationalPolicy) Transform {
+	return func(e *Entry) (*Entry, error) {
+		switch policy {
+		case OperationalStrip:
+			return stripOperational(e), nil
+		case OperationalRelax:
+			return relaxOperational(e), nil
+		}
+		return e, nil
+	}
+}
+
+func stripOperational(e *Entry) *Entry {
+	switch {
+	case e.Entry != nil:
+		entry := *e.Entry
+		entry.Attributes = slices.DeleteFunc(slices.Clone(entry.Attributes), func(a *ldap.EntryAttribute) bool {
+			return IsOperational(a.Name)
+		})
+		return &Entry{Entry: &entry}
+	case e.Add != nil:
+		add := *e.Add
+		add.Attributes = slices.DeleteFunc(slices.Clone(add.Attributes), func(a ldap.Attribute) bool {
+			return IsOperational(a.Type)
+		})
+		return &Entry{Add: &add}
+	case e.Modify != nil:
+		modify := *e.Modify
+		modify.Changes = slices.DeleteFunc(slices.Clone(modify.Changes), func(c ldap.Change) bool {
+			return IsOperational(c.Modification.Type)
+		})
+		if len(modify.Changes) == 0 {
+			// servers reject a modify without changes
+			return nil
+		}
+		return &Entry{Modify: &modify}
+	}
+	return e
+}
+
+func relaxOperational(e *Entry) *Entry {
+	relax := ldap.NewControlString(ControlTypeRelaxRules, true, "")
+	switch {
+	case e.Entry != nil:
+		if !slices.ContainsFunc(e.Entry.Attributes, func(a *ldap.EntryAttribute) bool { return IsOperational(a.Name) }) {
+			return e
+		}
+		add := ldap.NewAddRequest(e.Entry.DN, []ldap.Control{relax})
+		for _, a := range e.Entry.Attributes {
+			add.Attribute(a.Name, a.Values)
+		}
+		return &Entry{Add: add}
+	case e.Add != nil:
+		if !slices.ContainsFunc(e.Add.Attributes, func(a ldap.Attribute) bool { return IsOperational(a.Type) }) {
+			return e
+		}
+		add := *e.Add
+		add.Controls = append(slices.Clip(add.Controls), relax)
+		return &Entry{Add: &add}
+	case e.Modify != nil:
+		if !slices.ContainsFunc(e.Modify.Changes, func(c ldap.Change) bool { return IsOperational(c.Modification.Type) }) {
+			return e
+		}
+		modify := *e.Modify
+		modify.Controls = append(slices.Clip(modify.Controls), relax)
+		return &Entry{Modify: &modify}
+	}
+	return e
+}
+
+// operationalLast returns a copy of the attributes with the operational
+// ones moved to the end, the order is kept otherwise.
+func operationalLast[A any](attrs []A, name func(A) string) []A {
+	sorted := slices.Clone(attrs)
+	slices.SortStableFunc(sorted, func(a, b A) int {
+		ao, bo := IsOperational(name(a)), IsOperational(name(b))
+		switch {
+		case ao && !bo:
+			return 1
+		case bo && !ao:
+			return -1
+		}
+		return 0
+	})
+	return sorted
+}
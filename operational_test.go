@@ -0,0 +1,155 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var slapcatLDIF = `dn: uid=jdoe,dc=example,dc=org
+objectClass: inetOrgPerson
+structuralObjectClass: inetOrgPerson
+entryUUID: 0b6e5c3e-8f6a-103e-8e5b-8d1f3a1e6c11
+uid: jdoe
+creatorsName: cn=admin,dc=example,dc=org
+createTimestamp: 20240101000000Z
+cn: John Doe
+entryCSN: 20240101000000.000000Z#000000#000#000000
+`
+
+func TestHandleOperational(t *testing.T) {
+	l, err := ldif.Parse(slapcatLDIF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := l.Entries[0]
+
+	stripped, err := ldif.HandleOperational(ldif.OperationalStrip)(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range stripped.Entry.Attributes {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "cn,objectClass,uid" {
+		t.Errorf("strip: got %v", names)
+	}
+	if len(record.Entry.Attributes) != 8 {
+		t.Errorf("strip changed the record in place")
+	}
+
+	// attributes set by administrators are kept, even if their schema
+	// marks them as operational
+	admin := &ldif.Entry{Entry: ldap.NewEntry("uid=jdoe,dc=example,dc=org", map[string][]string{
+		"uid":                  {"jdoe"},
+		"modifyTimestamp":      {"20240101000000Z"},
+		"nsAccountLock":        {"true"},
+		"pwdAccountLockedTime": {"000001010000Z"},
+		"nsRoleDN":             {"cn=role,dc=example,dc=org"},
+		"pwdPolicySubentry":    {"cn=policy,dc=example,dc=org"},
+		"pwdReset":             {"TRUE"},
+		"primaryGroupID":       {"513"},
+		"memberOf":             {"cn=admins,dc=example,dc=org"},
+	})}
+	stripped, err = ldif.HandleOperational(ldif.OperationalStrip)(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = names[:0]
+	for _, a := range stripped.Entry.Attributes {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "memberOf,nsAccountLock,nsRoleDN,primaryGroupID,pwdAccountLockedTime,pwdPolicySubentry,pwdReset,uid" {
+		t.Errorf("strip: got %v", names)
+	}
+
+	relaxed, err := ldif.HandleOperational(ldif.OperationalRelax)(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relaxed.Add == nil || len(relaxed.Add.Attributes) != 8 || len(relaxed.Add.Controls) != 1 ||
+		relaxed.Add.Controls[0].GetControlType() != ldif.ControlTypeRelaxRules {
+		t.Fatalf("relax: got %+v", relaxed)
+	}
+
+	plain := &ldif.Entry{Entry: ldap.NewEntry("cn=foo,dc=example,dc=org", map[string][]string{"cn": {"foo"}})}
+	if e, _ := ldif.HandleOperational(ldif.OperationalRelax)(plain); e != plain {
+		t.Errorf("relax: record without operational attributes was changed")
+	}
+	if e, _ := ldif.HandleOperational(ldif.OperationalKeep)(record); e != record {
+		t.Errorf("keep: record was changed")
+	}
+}
+
+func TestApplyOperational(t *testing.T) {
+	for _, tc := range []struct {
+		policy   ldif.OperationalPolicy
+		attrs    int
+		controls int
+	}{
+		{ldif.OperationalKeep, 8, 0},
+		{ldif.OperationalStrip, 3, 0},
+		{ldif.OperationalRelax, 8, 1},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			l, err := ldif.Parse(slapcatLDIF)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Operational = tc.policy
+			conn := &recordingConn{}
+			if err := l.Apply(conn, false); err != nil {
+				t.Fatal(err)
+			}
+			if len(conn.adds) != 1 || len(conn.adds[0].Attributes) != tc.attrs || len(conn.adds[0].Controls) != tc.controls {
+				t.Errorf("unexpected add: %+v", conn.adds)
+			}
+		})
+	}
+
+	// a modify of operational attributes only is not sent with
+	// OperationalStrip, servers reject a modify without changes
+	mod := ldap.NewModifyRequest("uid=jdoe,dc=example,dc=org", nil)
+	mod.Replace("modifyTimestamp", []string{"20240101000000Z"})
+	mod.Replace("entryCSN", []string{"20240101000000.000000Z#000000#000#000000"})
+	if e, err := ldif.HandleOperational(ldif.OperationalStrip)(&ldif.Entry{Modify: mod}); e != nil || err != nil {
+		t.Errorf("strip: got %+v, %v", e, err)
+	}
+	l := &ldif.LDIF{Entries: []*ldif.Entry{{Modify: mod}, {Del: ldap.NewDelRequest("uid=jdoe,dc=example,dc=org", nil)}}, Operational: ldif.OperationalStrip}
+	conn := &recordingConn{}
+	if err := l.Apply(conn, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.mods) != 0 || len(conn.dels) != 1 {
+		t.Errorf("applied %d modifies, %d deletes", len(conn.mods), len(conn.dels))
+	}
+}
+
+func TestMarshalOperationalLast(t *testing.T) {
+	entry := &ldap.Entry{DN: "uid=jdoe,dc=example,dc=org", Attributes: []*ldap.EntryAttribute{
+		ldap.NewEntryAttribute("entryUUID", []string{"0b6e5c3e-8f6a-103e-8e5b-8d1f3a1e6c11"}),
+		ldap.NewEntryAttribute("objectClass", []string{"inetOrgPerson"}),
+		ldap.NewEntryAttribute("createTimestamp", []string{"20240101000000Z"}),
+		ldap.NewEntryAttribute("uid", []string{"jdoe"}),
+	}}
+	expected := `dn: uid=jdoe,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: jdoe
+entryUUID: 0b6e5c3e-8f6a-103e-8e5b-8d1f3a1e6c11
+createTimestamp: 20240101000000Z
+
+`
+	s, err := ldif.Marshal(&ldif.LDIF{Entries: []*ldif.Entry{{Entry: entry}}, OperationalLast: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+	if entry.Attributes[0].Name != "entryUUID" {
+		t.Errorf("the attributes of the entry were reordered")
+	}
+}
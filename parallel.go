@@ -0,0 +1,221 @@
+package ldif
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"runtime"
+)
+
+const (
+	minChunkSize = 64 << 10
+	maxChunkSize = 4 << 20
+)
+
+type chunkResult struct {
+	entries []*Entry
+	lines   int
+	version int
+	err     error
+}
+
+// UnmarshalEntriesParallel parses the LDIF in r (of the given size) with
+// several workers and yields the entries in their original order, like
+// UnmarshalEntries does.
+//
+// The input is split into chunks at record boundaries, i.e. at a blank line
+// which is not followed by a continuation line. Each chunk is parsed on its
+// own, so a record must not contain blank lines. With workers <= 0,
+// runtime.GOMAXPROCS(0) workers are used.
+//
+// The Version of l is set after the first chunk has been parsed, the other
+// fields are used as parser options for all workers. Limits.MaxEntries is
+// checked for the whole input, but as chunks are parsed ahead, the limit
+// does not bound the work done by the workers.
+//
+// UTF-16 input of ProfileActiveDirectory cannot be split at line ends, it is
+// parsed by UnmarshalEntries.
+func UnmarshalEntriesParallel(r io.ReaderAt, size int64, l *LDIF, workers int) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		if r == nil {
+			yield(nil, &ParseError{Line: 0, Message: "No reader present", Err: ErrNoReader})
+			return
+		}
+		if l.Profile == ProfileActiveDirectory {
+			head := make([]byte, len(utf16BOM))
+			if n, _ := r.ReadAt(head, 0); n == len(head) && bytes.Equal(head, utf16BOM) {
+				for e, err := range UnmarshalEntries(io.NewSectionReader(r, 0, size), l) {
+					if !yield(e, err) {
+						return
+					}
+				}
+				return
+			}
+		}
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		chunkSize := size / int64(workers*4)
+		chunkSize = max(minChunkSize, min(maxChunkSize, chunkSize))
+		bounds, err := splitRecords(r, size, chunkSize, l.Mode == ModeLenient)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		n := len(bounds) - 1
+		results := make([]chan chunkResult, n)
+		for i := range results {
+			results[i] = make(chan chunkResult, 1)
+		}
+		done := make(chan struct{})
+		defer close(done)
+		tokens := make(chan struct{}, workers*2)
+
+		go func() {
+			for i := 0; i < n; i++ {
+				select {
+				case tokens <- struct{}{}:
+				case <-done:
+					return
+				}
+				go func(i int) {
+					results[i] <- l.parseChunk(r, bounds[i], bounds[i+1], i == 0, done)
+				}(i)
+			}
+		}()
+
+		lines, entries, changes := 0, 0, 0
+		for i := 0; i < n; i++ {
+			res := <-results[i]
+			<-tokens
+			if i == 0 {
+				l.Version = res.version
+			}
+			if res.err != nil {
+				var perr *ParseError
+				if errors.As(res.err, &perr) {
+					perr.Line += lines
+				}
+				yield(nil, res.err)
+				return
+			}
+			for _, e := range res.entries {
+				entries++
+				if maxEntries := l.Limits.MaxEntries; maxEntries > 0 && entries > maxEntries {
+					// the line of the record is not known, report the start of the chunk
+					yield(nil, newParseError(lines+1, withDN(&LimitError{Limit: "MaxEntries", Max: maxEntries}, e.DN())))
+					return
+				}
+				if e.Entry == nil {
+					changes++
+				}
+				if l.Mode == ModeStrict && changes != 0 && changes != entries {
+					yield(nil, newParseError(lines+1, withDN(errorf(ErrMixed, "%s", ErrMixed), e.DN())))
+					return
+				}
+				if !yield(e, nil) {
+					return
+				}
+			}
+			lines += res.lines
+		}
+	}
+}
+
+func (l *LDIF) parseChunk(r io.ReaderAt, start, end int64, first bool, done <-chan struct{}) (res chunkResult) {
+	buf := make([]byte, end-start)
+	if _, err := r.ReadAt(buf, start); err != nil && err != io.EOF {
+		res.err = err
+		return
+	}
+	res.lines = bytes.Count(buf, []byte{lf})
+
+	chunk := &LDIF{
+		Controls: l.Controls,
+		Mode:     l.Mode,
+		Limits:   l.Limits,
+		Profile:  l.Profile,
+		DSMLURLs: l.DSMLURLs,
+	}
+	for e, err := range unmarshalRecords(bytes.NewReader(buf), chunk, first) {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if err == nil && e == nil {
+			continue
+		}
+		if err != nil {
+			res.err = err
+			return
+		}
+		res.entries = append(res.entries, e)
+	}
+	if first {
+		res.version = chunk.Version
+	}
+	return
+}
+
+// splitRecords returns the offsets of the chunk boundaries: 0, the start of
+// the first record after each multiple of chunkSize and size. With lenient,
+// a line starting with a tab is a continuation line, like in ModeLenient.
+func splitRecords(r io.ReaderAt, size, chunkSize int64, lenient bool) ([]int64, error) {
+	bounds := []int64{0}
+	for target := chunkSize; target < size; target += chunkSize {
+		if target <= bounds[len(bounds)-1] {
+			continue
+		}
+		off, err := nextRecordBoundary(r, target, size, lenient)
+		if err != nil {
+			return nil, err
+		}
+		if off >= size {
+			break
+		}
+		bounds = append(bounds, off)
+		if off > target {
+			target = off - off%chunkSize
+		}
+	}
+	return append(bounds, size), nil
+}
+
+// nextRecordBoundary returns the offset of the first line after off which
+// follows a blank line and is not a continuation line, or size if there is
+// none.
+func nextRecordBoundary(r io.ReaderAt, off, size int64, lenient bool) (int64, error) {
+	pos := off - 1
+	reader := bufio.NewReader(io.NewSectionReader(r, pos, size-pos))
+	atLineStart := false // the first read is the rest of a line
+	afterBlank := false
+	for {
+		if afterBlank {
+			next, err := reader.Peek(1)
+			if err == io.EOF {
+				return size, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			if next[0] != space && (next[0] != tab || !lenient) {
+				return pos, nil
+			}
+		}
+		line, err := reader.ReadSlice(lf)
+		if err != nil && err != bufio.ErrBufferFull {
+			if err == io.EOF {
+				return size, nil
+			}
+			return 0, err
+		}
+		pos += int64(len(line))
+		afterBlank = atLineStart && err == nil && len(bytes.TrimRight(line, sep)) == 0
+		atLineStart = err == nil
+	}
+}
@@ -0,0 +1,126 @@
+package ldif_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+func TestUnmarshalEntriesParallel(t *testing.T) {
+	_, data := nPeople(3000)
+	data = "version: 1\n" + data
+	r := strings.NewReader(data)
+
+	var sequential []*ldif.Entry
+	for e, err := range ldif.UnmarshalEntries(strings.NewReader(data), &ldif.LDIF{}) {
+		if err != nil {
+			t.Fatalf("UnmarshalEntries: %s", err)
+		}
+		sequential = append(sequential, e)
+	}
+
+	for _, workers := range []int{0, 1, 3, 8} {
+		l := &ldif.LDIF{}
+		var parallel []*ldif.Entry
+		for e, err := range ldif.UnmarshalEntriesParallel(r, r.Size(), l, workers) {
+			if err != nil {
+				t.Fatalf("UnmarshalEntriesParallel(%d): %s", workers, err)
+			}
+			parallel = append(parallel, e)
+		}
+		if l.Version != 1 {
+			t.Errorf("workers %d: version not set", workers)
+		}
+		if len(parallel) != len(sequential) {
+			t.Fatalf("workers %d: got %d entries, expected %d", workers, len(parallel), len(sequential))
+		}
+		for i := range parallel {
+			if parallel[i].Entry.DN != sequential[i].Entry.DN {
+				t.Fatalf("workers %d: entry %d is %s, expected %s", workers, i, parallel[i].Entry.DN, sequential[i].Entry.DN)
+			}
+		}
+	}
+}
+
+func TestUnmarshalEntriesParallelError(t *testing.T) {
+	_, data := nPeople(3000)
+	data += "dn: uid=broken,dc=example,dc=org\nsn:: XXX-U29tZSBPbmU=\n"
+	wantLine := strings.Count(data, "\n") + 1
+
+	r := strings.NewReader(data)
+	var got error
+	for _, err := range ldif.UnmarshalEntriesParallel(r, r.Size(), &ldif.LDIF{}, 4) {
+		if err != nil {
+			got = err
+		}
+	}
+	var perr *ldif.ParseError
+	if !errors.As(got, &perr) {
+		t.Fatalf("expected ParseError, got %v", got)
+	}
+	if perr.Line != wantLine {
+		t.Errorf("error in line %d, expected %d", perr.Line, wantLine)
+	}
+}
+
+func TestUnmarshalEntriesParallelBreak(t *testing.T) {
+	_, data := nPeople(3000)
+	r := strings.NewReader(data)
+	n := 0
+	for range ldif.UnmarshalEntriesParallel(r, r.Size(), &ldif.LDIF{}, 4) {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("expected to stop after 10 entries, got %d", n)
+	}
+}
+
+func TestUnmarshalEntriesParallelTabContinuation(t *testing.T) {
+	_, data := nPeople(3000)
+	// continue a comment with a tab across the blank line at the first
+	// chunk boundary
+	i := 64<<10 + strings.Index(data[64<<10:], "\n\n") + 1
+	data = data[:i] + "# a comment\n\n\tcontinued\n" + data[i+1:]
+
+	parse := func(seq func(func(*ldif.Entry, error) bool)) (int, error) {
+		n := 0
+		for _, err := range seq {
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	}
+	l := &ldif.LDIF{Mode: ldif.ModeLenient}
+	wantN, want := parse(ldif.UnmarshalEntries(strings.NewReader(data), l))
+	r := strings.NewReader(data)
+	gotN, got := parse(ldif.UnmarshalEntriesParallel(r, r.Size(), l, 4))
+	if gotN != wantN || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %d entries and error %v, expected %d entries and error %v", gotN, got, wantN, want)
+	}
+}
+
+func TestUnmarshalEntriesParallelUTF16(t *testing.T) {
+	r := strings.NewReader(utf16LE(strings.Repeat(ldifdeExport, 1000)))
+	l := &ldif.LDIF{Profile: ldif.ProfileActiveDirectory}
+	n := 0
+	for e, err := range ldif.UnmarshalEntriesParallel(r, r.Size(), l, 4) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Modify != nil && e.Modify.Changes[0].Modification.Vals[0] != "Jörg's group" {
+			t.Fatalf("got description %q", e.Modify.Changes[0].Modification.Vals[0])
+		}
+		n++
+	}
+	if n != 2000 {
+		t.Errorf("got %d records, expected 2000", n)
+	}
+}
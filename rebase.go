@@ -0,0 +1,154 @@
+package ldif
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DNAttributes are the attributes with DN syntax whose values are rewritten
+// by Rebase if no other attributes are given.
+var DNAttributes = []string{
+	"member", "uniqueMember", "manager", "owner", "seeAlso", "secretary",
+	"roleOccupant", "memberOf", "distinguishedName", "aliasedObjectName",
+	"nsRoleDN", "directReports", "managedBy",
+}
+
+// dnString returns the string form of the DN (RFC 4514).
+func dnString(dn *ldap.DN) string {
+	rdns := make([]string, len(dn.RDNs))
+	for i, rdn := range dn.RDNs {
+		avas := make([]string, len(rdn.Attributes))
+		for j, ava := range rdn.Attributes {
+			avas[j] = ava.Type + "=" + escapeDNValue(ava.Value)
+		}
+		rdns[i] = strings.Join(avas, "+")
+	}
+	return strings.Join(rdns, ",")
+}
+
+// rebaser replaces the suffix from of DNs with to.
+type rebaser struct {
+	from  []string // normalized RDNs
+	to    string
+	attrs map[string]bool
+}
+
+func newRebaser(from, to string, attributes []string) (*rebaser, error) {
+	parsed, err := ldap.ParseDN(from)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, from, err)
+	}
+	if _, err := ldap.ParseDN(to); err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, to, err)
+	}
+	if attributes == nil {
+		attributes = DNAttributes
+	}
+	r := &rebaser{from: rdnKeys(parsed), to: to, attrs: make(map[string]bool, len(attributes))}
+	for _, a := range attributes {
+		r.attrs[strings.ToLower(a)] = true
+	}
+	return r, nil
+}
+
+// dn returns the rebased DN and true, if the DN is within from.
+func (r *rebaser) dn(dn *ldap.DN) (string, bool) {
+	if !hasSuffix(dn, r.from) {
+		return "", false
+	}
+	n := len(dn.RDNs) - len(r.from)
+	prefix := dnString(&ldap.DN{RDNs: dn.RDNs[:n]})
+	switch {
+	case prefix == "":
+		return r.to, true
+	case r.to == "":
+		return prefix, true
+	}
+	return prefix + "," + r.to, true
+}
+
+// recordDN rebases the DN of a record, which must be valid.
+func (r *rebaser) recordDN(dn *string) error {
+	parsed, err := ldap.ParseDN(*dn)
+	if err != nil {
+		return fmt.Errorf("%w %q: %s", ErrInvalidDN, *dn, err)
+	}
+	if rebased, ok := r.dn(parsed); ok {
+		*dn = rebased
+	}
+	return nil
+}
+
+// values returns the rebased values of the attribute, values which are not
+// DNs are kept.
+func (r *rebaser) values(attr string, values []string) []string {
+	name, _, _ := strings.Cut(attr, ";")
+	if !r.attrs[strings.ToLower(name)] {
+		return values
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = v
+		if parsed, err := ldap.ParseDN(v); err == nil {
+			if rebased, ok := r.dn(parsed); ok {
+				result[i] = rebased
+			}
+		}
+	}
+	return result
+}
+
+func (r *rebaser) entry(e *Entry) error {
+	switch {
+	case e.Entry != nil:
+		for _, a := range e.Entry.Attributes {
+			setValues(a, r.values(a.Name, a.Values))
+		}
+		return r.recordDN(&e.Entry.DN)
+	case e.Add != nil:
+		for i, a := range e.Add.Attributes {
+			e.Add.Attributes[i].Vals = r.values(a.Type, a.Vals)
+		}
+		return r.recordDN(&e.Add.DN)
+	case e.Del != nil:
+		return r.recordDN(&e.Del.DN)
+	case e.Modify != nil:
+		for i, c := range e.Modify.Changes {
+			e.Modify.Changes[i].Modification.Vals = r.values(c.Modification.Type, c.Modification.Vals)
+		}
+		return r.recordDN(&e.Modify.DN)
+	case e.ModifyDN != nil:
+		if e.ModifyDN.NewSuperior != "" {
+			if err := r.recordDN(&e.ModifyDN.NewSuperior); err != nil {
+				return err
+			}
+		}
+		return r.recordDN(&e.ModifyDN.DN)
+	}
+	return nil
+}
+
+// Rebase moves all records of the LDIF below the DN from to the DN to, e.g.
+// from "dc=old,dc=com" to "dc=new,dc=org". It rewrites the DNs of all
+// records, the newsuperior of modrdn records and the values of the given
+// DN-valued attributes (DNAttributes if attributes is nil), if they are
+// within from. DNs are compared as parsed DNs and case-insensitively, so
+// "DC=Old, DC=Com" matches as well.
+//
+// The part of a DN below from is written in the RFC 4514 string form, to is
+// used as given. Values of the attributes which are no DNs are kept, an
+// invalid DN of a record is an error.
+func (l *LDIF) Rebase(from, to string, attributes []string) error {
+	r, err := newRebaser(from, to, attributes)
+	if err != nil {
+		return err
+	}
+	for _, e := range l.Entries {
+		if err := r.entry(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
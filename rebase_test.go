@@ -0,0 +1,109 @@
+package ldif_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var rebaseLDIF = `dn: cn=admins,ou=Groups,dc=old,dc=com
+changetype: modify
+add: member
+member: uid=jdoe,ou=People,dc=old,dc=com
+member: not a DN
+-
+
+dn: uid=asmith,ou=People,dc=old,dc=com
+changetype: modrdn
+newrdn: uid=anna
+deleteoldrdn: 1
+newsuperior: ou=Former,dc=old,dc=com
+
+dn: dc=old,dc=com
+changetype: delete
+
+`
+
+var rebasedLDIF = `dn: cn=admins,ou=Groups,dc=new,dc=org
+changetype: modify
+add: member
+member: uid=jdoe,ou=People,dc=new,dc=org
+member: not a DN
+-
+
+dn: uid=asmith,ou=People,dc=new,dc=org
+changetype: modrdn
+newrdn: uid=anna
+deleteoldrdn: 1
+newsuperior: ou=Former,dc=new,dc=org
+
+dn: dc=new,dc=org
+changetype: delete
+
+`
+
+func TestRebase(t *testing.T) {
+	l, err := ldif.Parse(rebaseLDIF)
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if err := l.Rebase("dc=old,dc=com", "dc=new,dc=org", nil); err != nil {
+		t.Fatalf("rebase: %s", err)
+	}
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	if out != rebasedLDIF {
+		t.Errorf("got:\n%s\nexpected:\n%s", out, rebasedLDIF)
+	}
+
+	l, err = ldif.Parse("dn: uid=jdoe,ou=People,dc=old,dc=com\nchangetype: add\nuid: jdoe\n" +
+		"manager: uid=boss,ou=People,DC=Old, DC=Com\nseeAlso: cn=other,dc=example,dc=net\ndescription: uid=jdoe,dc=old,dc=com\n")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if err := l.Rebase("dc=old,dc=com", "dc=new,dc=org", nil); err != nil {
+		t.Fatalf("rebase: %s", err)
+	}
+	add := l.Entries[0].Add
+	if add.DN != "uid=jdoe,ou=People,dc=new,dc=org" {
+		t.Errorf("wrong DN %q", add.DN)
+	}
+	expected := map[string]string{
+		"uid":         "jdoe",
+		"manager":     "uid=boss,ou=People,dc=new,dc=org",
+		"seeAlso":     "cn=other,dc=example,dc=net",
+		"description": "uid=jdoe,dc=old,dc=com",
+	}
+	for _, a := range add.Attributes {
+		if a.Vals[0] != expected[a.Type] {
+			t.Errorf("%s: got %q, expected %q", a.Type, a.Vals[0], expected[a.Type])
+		}
+	}
+}
+
+func TestRebaseContent(t *testing.T) {
+	l, err := ldif.Parse("dn: cn=foo\\,bar,ou=Groups,dc=old,dc=com\ncn: foo,bar\nmember: uid=jdoe,dc=old,dc=com\nowner: uid=jdoe,dc=old,dc=com\n")
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if err := l.Rebase("dc=old,dc=com", "o=new", []string{"Member"}); err != nil {
+		t.Fatalf("rebase: %s", err)
+	}
+	e := l.Entries[0].Entry
+	if e.DN != "cn=foo\\,bar,ou=Groups,o=new" {
+		t.Errorf("wrong DN %q", e.DN)
+	}
+	if v := e.GetAttributeValue("member"); v != "uid=jdoe,o=new" {
+		t.Errorf("wrong member %q", v)
+	}
+	if v := e.GetAttributeValue("owner"); v != "uid=jdoe,dc=old,dc=com" {
+		t.Errorf("owner is not in the attributes, but rewritten to %q", v)
+	}
+
+	if err := l.Rebase("dc=old,,", "o=new", nil); !errors.Is(err, ldif.ErrInvalidDN) {
+		t.Errorf("invalid from: got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package ldif
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SplitOptions configure Split. Without any option, all records are
+// written to one file.
+type SplitOptions struct {
+	// Subtrees are the DNs of subtrees which get their own files, a record
+	// goes to the most specific subtree containing its DN. The records
+	// outside all subtrees get their own files, too.
+	Subtrees []string
+	// MaxRecords starts a new file after that many records, if > 0.
+	MaxRecords int
+	// MaxBytes starts a new file before a record which would make it larger
+	// than that, if > 0. A single larger record gets a file on its own.
+	MaxBytes int64
+	// FoldWidth is the line width of the output, see LDIF.FoldWidth.
+	FoldWidth int
+}
+
+// A SplitPart identifies one file written by Split.
+type SplitPart struct {
+	// Subtree is the subtree (as given in SplitOptions.Subtrees) of the
+	// records, empty for the records outside all subtrees.
+	Subtree string
+	// Index counts the files of the subtree, starting with 1.
+	Index int
+}
+
+type splitOutput struct {
+	part    SplitPart
+	w       io.WriteCloser
+	records int
+	size    int64
+}
+
+// Split reads the records from entries and writes them to the files which
+// are created by create. Each file starts with a "version: 1" line, so it
+// is a valid LDIF of its own. The records keep their order within each
+// file. An error of entries, create or a writer ends the split; all files
+// created so far are closed.
+func Split(entries iter.Seq2[*Entry, error], opts *SplitOptions, create func(SplitPart) (io.WriteCloser, error)) (err error) {
+	if opts == nil {
+		opts = &SplitOptions{}
+	}
+	type subtree struct {
+		name   string
+		suffix []string
+	}
+	var subtrees []subtree
+	for _, s := range opts.Subtrees {
+		dn, err := ldap.ParseDN(s)
+		if err != nil {
+			return fmt.Errorf("%w %q: %s", ErrInvalidDN, s, err)
+		}
+		subtrees = append(subtrees, subtree{name: s, suffix: rdnKeys(dn)})
+	}
+	// the most specific subtree first
+	slices.SortStableFunc(subtrees, func(a, b subtree) int { return len(b.suffix) - len(a.suffix) })
+
+	outputs := make(map[string]*splitOutput)
+	defer func() {
+		for _, out := range outputs {
+			if cerr := out.w.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var buf bytes.Buffer
+	l := &LDIF{FoldWidth: opts.FoldWidth, Entries: make([]*Entry, 1)}
+	for entry, err := range entries {
+		if err != nil {
+			return err
+		}
+		name := ""
+		if len(subtrees) > 0 {
+			dn, err := ldap.ParseDN(entry.DN())
+			if err != nil {
+				return fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN(), err)
+			}
+			for _, s := range subtrees {
+				if hasSuffix(dn, s.suffix) {
+					name = s.name
+					break
+				}
+			}
+		}
+
+		buf.Reset()
+		l.Entries[0] = entry
+		if err := MarshalStreaming(l, &buf); err != nil {
+			return err
+		}
+
+		out := outputs[name]
+		full := out != nil && ((opts.MaxRecords > 0 && out.records >= opts.MaxRecords) ||
+			(opts.MaxBytes > 0 && out.records > 0 && out.size+int64(buf.Len()) > opts.MaxBytes))
+		if out == nil || full {
+			part := SplitPart{Subtree: name, Index: 1}
+			if out != nil {
+				part.Index = out.part.Index + 1
+				delete(outputs, name)
+				if err := out.w.Close(); err != nil {
+					return err
+				}
+			}
+			w, err := create(part)
+			if err != nil {
+				return err
+			}
+			out = &splitOutput{part: part, w: w}
+			outputs[name] = out
+			n, err := io.WriteString(w, "version: 1\n")
+			if err != nil {
+				return err
+			}
+			out.size = int64(n)
+		}
+		if _, err := out.w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		out.records++
+		out.size += int64(buf.Len())
+	}
+	return nil
+}
+
+// A MergePolicy says how Merge handles entries with the same DN.
+type MergePolicy int
+
+const (
+	// MergeFirstWins keeps the first entry.
+	MergeFirstWins MergePolicy = iota
+	// MergeLastWins keeps the last entry, at the position of the first.
+	MergeLastWins
+	// MergeValues merges the values of all entries into the first one,
+	// attribute names are compared case-insensitively, values exactly.
+	MergeValues
+	// MergeError returns an error wrapping ErrDuplicateDN.
+	MergeError
+)
+
+func (p MergePolicy) String() string {
+	switch p {
+	case MergeFirstWins:
+		return "first"
+	case MergeLastWins:
+		return "last"
+	case MergeValues:
+		return "merge"
+	case MergeError:
+		return "error"
+	}
+	return fmt.Sprintf("MergePolicy(%d)", int(p))
+}
+
+// Merge reads the content records of all inputs and returns them as one
+// list, entries with the same (normalized) DN are handled as given by the
+// policy. The entries are in the order in which their DN appears first. Add
+// records are merged like content records, other change records are an
+// error. All entries are kept in memory.
+func Merge(inputs []iter.Seq2[*Entry, error], policy MergePolicy) ([]*Entry, error) {
+	var merged []*Entry
+	byDN := make(map[string]int)
+	for _, input := range inputs {
+		for record, err := range input {
+			if err != nil {
+				return nil, err
+			}
+			entry := contentEntry(record)
+			if entry == nil {
+				return nil, fmt.Errorf("%s is a change record, merge needs content records", record.DN())
+			}
+			key, err := normalizeDN(entry.DN)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN, err)
+			}
+			i, ok := byDN[key]
+			if !ok {
+				byDN[key] = len(merged)
+				merged = append(merged, &Entry{Entry: entry})
+				continue
+			}
+			switch policy {
+			case MergeLastWins:
+				merged[i] = &Entry{Entry: entry}
+			case MergeValues:
+				merged[i] = &Entry{Entry: mergeValues(merged[i].Entry, entry)}
+			case MergeError:
+				return nil, fmt.Errorf("%w %s", ErrDuplicateDN, entry.DN)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// mergeValues returns a copy of the entry with the attributes and values of
+// from added, the entries of the inputs are not changed.
+func mergeValues(entry, from *ldap.Entry) *ldap.Entry {
+	merged := &ldap.Entry{DN: entry.DN, Attributes: make([]*ldap.EntryAttribute, len(entry.Attributes))}
+	for i, a := range entry.Attributes {
+		merged.Attributes[i] = ldap.NewEntryAttribute(a.Name, slices.Clone(a.Values))
+	}
+	for _, a := range from.Attributes {
+		var existing *ldap.EntryAttribute
+		for _, b := range merged.Attributes {
+			if strings.EqualFold(a.Name, b.Name) {
+				existing = b
+				break
+			}
+		}
+		if existing == nil {
+			merged.Attributes = append(merged.Attributes, ldap.NewEntryAttribute(a.Name, slices.Clone(a.Values)))
+			continue
+		}
+		values := existing.Values
+		for _, v := range a.Values {
+			if !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		setValues(existing, values)
+	}
+	return merged
+}
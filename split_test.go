@@ -0,0 +1,207 @@
+package ldif_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var splitLDIF = `dn: dc=example,dc=org
+dc: example
+
+dn: ou=People,dc=example,dc=org
+ou: People
+
+dn: uid=jdoe,ou=People,dc=example,dc=org
+uid: jdoe
+
+dn: ou=Groups,dc=example,dc=org
+ou: Groups
+
+dn: cn=admins,OU=groups,dc=example,dc=org
+cn: admins
+`
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+// split returns the DNs of the records of each part and checks that each
+// part is a valid LDIF starting with a version line.
+func split(t *testing.T, opts *ldif.SplitOptions) map[ldif.SplitPart][]string {
+	t.Helper()
+	var parts []ldif.SplitPart
+	files := make(map[ldif.SplitPart]*bufferCloser)
+	err := ldif.Split(ldif.UnmarshalEntries(strings.NewReader(splitLDIF), &ldif.LDIF{}), opts, func(part ldif.SplitPart) (io.WriteCloser, error) {
+		parts = append(parts, part)
+		files[part] = &bufferCloser{}
+		return files[part], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dns := make(map[ldif.SplitPart][]string)
+	for _, part := range parts {
+		f := files[part]
+		if !f.closed {
+			t.Errorf("%v not closed", part)
+		}
+		if !strings.HasPrefix(f.String(), "version: 1\n") {
+			t.Errorf("%v has no version line:\n%s", part, f.String())
+		}
+		l := &ldif.LDIF{}
+		if err := ldif.Unmarshal(&f.Buffer, l); err != nil {
+			t.Fatalf("%v: %s", part, err)
+		}
+		for _, e := range l.Entries {
+			dns[part] = append(dns[part], e.DN())
+		}
+	}
+	return dns
+}
+
+func TestSplitRecords(t *testing.T) {
+	dns := split(t, &ldif.SplitOptions{MaxRecords: 2})
+	if len(dns) != 3 || len(dns[ldif.SplitPart{Index: 1}]) != 2 || len(dns[ldif.SplitPart{Index: 3}]) != 1 {
+		t.Errorf("unexpected parts: %v", dns)
+	}
+	if dns := split(t, nil); len(dns) != 1 || len(dns[ldif.SplitPart{Index: 1}]) != 5 {
+		t.Errorf("without options: %v", dns)
+	}
+}
+
+func TestSplitBytes(t *testing.T) {
+	// "version: 1\n" and two records of this size
+	dns := split(t, &ldif.SplitOptions{MaxBytes: 11 + 50 + 50})
+	for part, records := range dns {
+		if len(records) == 0 || len(records) > 2 {
+			t.Errorf("%v: %v", part, records)
+		}
+	}
+	if len(dns) != 3 {
+		t.Errorf("unexpected parts: %v", dns)
+	}
+	// a record larger than MaxBytes gets its own file
+	if dns := split(t, &ldif.SplitOptions{MaxBytes: 1}); len(dns) != 5 {
+		t.Errorf("small MaxBytes: %v", dns)
+	}
+}
+
+func TestSplitSubtrees(t *testing.T) {
+	dns := split(t, &ldif.SplitOptions{Subtrees: []string{"dc=example,dc=org", "ou=groups,dc=example,dc=org"}})
+	expected := map[ldif.SplitPart][]string{
+		{Subtree: "dc=example,dc=org", Index: 1}:           {"dc=example,dc=org", "ou=People,dc=example,dc=org", "uid=jdoe,ou=People,dc=example,dc=org"},
+		{Subtree: "ou=groups,dc=example,dc=org", Index: 1}: {"ou=Groups,dc=example,dc=org", "cn=admins,OU=groups,dc=example,dc=org"},
+	}
+	if len(dns) != len(expected) {
+		t.Errorf("unexpected parts: %v", dns)
+	}
+	for part, records := range expected {
+		if !slices.Equal(dns[part], records) {
+			t.Errorf("%v: expected %v, got %v", part, records, dns[part])
+		}
+	}
+
+	dns = split(t, &ldif.SplitOptions{Subtrees: []string{"ou=People,dc=example,dc=org"}, MaxRecords: 2})
+	if len(dns[ldif.SplitPart{Index: 1}]) != 2 || len(dns[ldif.SplitPart{Index: 2}]) != 1 ||
+		len(dns[ldif.SplitPart{Subtree: "ou=People,dc=example,dc=org", Index: 1}]) != 2 {
+		t.Errorf("outside the subtrees: %v", dns)
+	}
+
+	err := ldif.Split(ldif.UnmarshalEntries(strings.NewReader(splitLDIF), &ldif.LDIF{}), &ldif.SplitOptions{Subtrees: []string{"invalid"}}, nil)
+	if !errors.Is(err, ldif.ErrInvalidDN) {
+		t.Errorf("invalid subtree: got %v", err)
+	}
+}
+
+var (
+	mergeA = "dn: cn=foo,dc=example,dc=org\ncn: foo\nmail: foo@example.org\n\ndn: cn=bar,dc=example,dc=org\ncn: bar\n"
+	mergeB = "dn: CN=Foo,dc=example,dc=org\ncn: foo\nmail: foo@example.com\ntelephoneNumber: 123\n\ndn: cn=baz,dc=example,dc=org\ncn: baz\n"
+)
+
+func merge(policy ldif.MergePolicy) ([]*ldif.Entry, error) {
+	return ldif.Merge([]iter.Seq2[*ldif.Entry, error]{
+		ldif.UnmarshalEntries(strings.NewReader(mergeA), &ldif.LDIF{}),
+		ldif.UnmarshalEntries(strings.NewReader(mergeB), &ldif.LDIF{}),
+	}, policy)
+}
+
+func TestMerge(t *testing.T) {
+	for _, tc := range []struct {
+		policy ldif.MergePolicy
+		dn     string
+		mail   []string
+		phone  []string
+	}{
+		{ldif.MergeFirstWins, "cn=foo,dc=example,dc=org", []string{"foo@example.org"}, nil},
+		{ldif.MergeLastWins, "CN=Foo,dc=example,dc=org", []string{"foo@example.com"}, []string{"123"}},
+		{ldif.MergeValues, "cn=foo,dc=example,dc=org", []string{"foo@example.org", "foo@example.com"}, []string{"123"}},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			entries, err := merge(tc.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dns []string
+			for _, e := range entries {
+				dns = append(dns, e.DN())
+			}
+			if !slices.Equal(dns, []string{tc.dn, "cn=bar,dc=example,dc=org", "cn=baz,dc=example,dc=org"}) {
+				t.Fatalf("unexpected entries: %v", dns)
+			}
+			foo := entries[0].Entry
+			if mail := foo.GetAttributeValues("mail"); !slices.Equal(mail, tc.mail) {
+				t.Errorf("expected mail %v, got %v", tc.mail, mail)
+			}
+			if phone := foo.GetAttributeValues("telephoneNumber"); !slices.Equal(phone, tc.phone) {
+				t.Errorf("expected telephoneNumber %v, got %v", tc.phone, phone)
+			}
+			if cn := foo.GetAttributeValues("cn"); len(cn) != 1 {
+				t.Errorf("duplicate cn values: %v", cn)
+			}
+		})
+	}
+
+	// MergeValues must not change the entries of the inputs
+	first := ldap.NewEntry("cn=foo,dc=example,dc=org", map[string][]string{"mail": {"a@example.org"}})
+	first.Attributes[0].Values = make([]string, 1, 4) // room to append in place
+	first.Attributes[0].Values[0] = "a@example.org"
+	second := ldap.NewEntry("cn=foo,dc=example,dc=org", map[string][]string{"mail": {"b@example.org"}, "cn": {"foo"}})
+	records := func(entries ...*ldap.Entry) iter.Seq2[*ldif.Entry, error] {
+		return func(yield func(*ldif.Entry, error) bool) {
+			for _, e := range entries {
+				if !yield(&ldif.Entry{Entry: e}, nil) {
+					return
+				}
+			}
+		}
+	}
+	entries, err := ldif.Merge([]iter.Seq2[*ldif.Entry, error]{records(first), records(second)}, ldif.MergeValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mail := entries[0].Entry.GetAttributeValues("mail"); !slices.Equal(mail, []string{"a@example.org", "b@example.org"}) {
+		t.Errorf("MergeValues: got mail %v", mail)
+	}
+	if len(first.Attributes) != 1 || !slices.Equal(first.Attributes[0].Values, []string{"a@example.org"}) ||
+		!slices.Equal(first.Attributes[0].Values[:2], []string{"a@example.org", ""}) {
+		t.Errorf("MergeValues changed the input entry: %v", first.Attributes[0].Values[:2])
+	}
+
+	if _, err := merge(ldif.MergeError); !errors.Is(err, ldif.ErrDuplicateDN) {
+		t.Errorf("MergeError: got %v", err)
+	}
+}
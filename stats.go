@@ -0,0 +1,290 @@
+package ldif
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// sizeBounds are the upper bounds of the value size histograms, the last
+// bucket has no bound.
+var sizeBounds = []int{16, 64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10}
+
+// A SizeBucket is a bucket of a value size histogram.
+type SizeBucket struct {
+	// Max is the largest size in bytes of the values in the bucket, 0 for
+	// the last bucket, which has no limit.
+	Max   int `json:"max,omitempty"`
+	Count int `json:"count"`
+}
+
+func newHistogram() []SizeBucket {
+	buckets := make([]SizeBucket, len(sizeBounds)+1)
+	for i, max := range sizeBounds {
+		buckets[i].Max = max
+	}
+	return buckets
+}
+
+func addSize(buckets []SizeBucket, size int) {
+	i, _ := slices.BinarySearch(sizeBounds, size)
+	buckets[i].Count++
+}
+
+// AttributeStats are the statistics of one attribute.
+type AttributeStats struct {
+	// Entries is the number of entries with the attribute.
+	Entries int `json:"entries"`
+	Values  int `json:"values"`
+	// Bytes is the total size of the values.
+	Bytes int64 `json:"bytes"`
+	// MaxSize is the size of the largest value.
+	MaxSize    int          `json:"maxSize"`
+	ValueSizes []SizeBucket `json:"valueSizes"`
+}
+
+// An EntrySize is the size of an entry, see Stats.Largest.
+type EntrySize struct {
+	DN   string `json:"dn"`
+	Size int    `json:"size"`
+}
+
+// StatsOptions configure NewStats.
+type StatsOptions struct {
+	// Largest is the number of entries in Stats.Largest, 10 if 0.
+	Largest int
+}
+
+// Stats is a profile of the records of an LDIF, collected by its Add method
+// or by CollectStats. Content and add records count as entries, the
+// statistics of object classes, depths and attributes are based on them.
+// Names of object classes and attributes are compared case-insensitively,
+// the first spelling is kept. Attribute options are part of the name.
+type Stats struct {
+	Records int `json:"records"`
+	Entries int `json:"entries"`
+	// ChangeTypes are the numbers of change records by changetype (add,
+	// delete, modify and moddn).
+	ChangeTypes map[string]int `json:"changeTypes"`
+	// ObjectClasses are the numbers of entries by object class.
+	ObjectClasses map[string]int `json:"objectClasses"`
+	// Depths are the numbers of entries by the number of RDNs of their DN.
+	Depths     map[int]int                `json:"depths"`
+	Attributes map[string]*AttributeStats `json:"attributes"`
+	// ValueSizes is the histogram of the sizes of all values.
+	ValueSizes []SizeBucket `json:"valueSizes"`
+	// BinaryValues and BinaryBytes are the number and size of the values
+	// which are not valid UTF-8 or belong to an attribute with the
+	// ";binary" option.
+	BinaryValues int   `json:"binaryValues"`
+	BinaryBytes  int64 `json:"binaryBytes"`
+	// Largest are the largest entries, the largest first. The size of an
+	// entry is the size of its DN plus the sizes of all attribute names and
+	// values, it is close to the size of its LDIF record.
+	Largest []EntrySize `json:"largest"`
+
+	largest int
+	names   map[string]string // lower cased name -> first spelling
+}
+
+// NewStats returns empty statistics.
+func NewStats(opts *StatsOptions) *Stats {
+	if opts == nil {
+		opts = &StatsOptions{}
+	}
+	s := &Stats{
+		ChangeTypes:   make(map[string]int),
+		ObjectClasses: make(map[string]int),
+		Depths:        make(map[int]int),
+		Attributes:    make(map[string]*AttributeStats),
+		ValueSizes:    newHistogram(),
+		largest:       opts.Largest,
+		names:         make(map[string]string),
+	}
+	if s.largest <= 0 {
+		s.largest = 10
+	}
+	return s
+}
+
+// name returns the first spelling of the name with the given prefix, which
+// keeps object classes and attributes apart.
+func (s *Stats) name(prefix, name string) string {
+	key := prefix + strings.ToLower(name)
+	if first, ok := s.names[key]; ok {
+		return first
+	}
+	s.names[key] = name
+	return name
+}
+
+// Add adds the record to the statistics. An error wrapping ErrInvalidDN is
+// returned for a content or add record with an invalid DN.
+func (s *Stats) Add(record *Entry) error {
+	s.Records++
+	switch {
+	case record.Add != nil:
+		s.ChangeTypes["add"]++
+	case record.Del != nil:
+		s.ChangeTypes["delete"]++
+		return nil
+	case record.Modify != nil:
+		s.ChangeTypes["modify"]++
+		return nil
+	case record.ModifyDN != nil:
+		s.ChangeTypes["moddn"]++
+		return nil
+	}
+	entry := contentEntry(record)
+	if entry == nil {
+		return nil
+	}
+	dn, err := ldap.ParseDN(entry.DN)
+	if err != nil {
+		return fmt.Errorf("%w %q: %s", ErrInvalidDN, entry.DN, err)
+	}
+	s.Entries++
+	s.Depths[len(dn.RDNs)]++
+
+	size := len(entry.DN)
+	for _, a := range entry.Attributes {
+		name := s.name("a:", a.Name)
+		stats := s.Attributes[name]
+		if stats == nil {
+			stats = &AttributeStats{ValueSizes: newHistogram()}
+			s.Attributes[name] = stats
+		}
+		stats.Entries++
+		_, options, _ := strings.Cut(strings.ToLower(a.Name), ";")
+		binary := slices.Contains(strings.Split(options, ";"), "binary")
+		for _, v := range a.Values {
+			stats.Values++
+			stats.Bytes += int64(len(v))
+			stats.MaxSize = max(stats.MaxSize, len(v))
+			addSize(stats.ValueSizes, len(v))
+			addSize(s.ValueSizes, len(v))
+			if binary || !utf8.ValidString(v) {
+				s.BinaryValues++
+				s.BinaryBytes += int64(len(v))
+			}
+			size += len(a.Name) + len(v)
+		}
+		if strings.EqualFold(a.Name, "objectClass") {
+			for _, v := range a.Values {
+				s.ObjectClasses[s.name("o:", v)]++
+			}
+		}
+	}
+
+	// after the entries of the same size
+	i, _ := slices.BinarySearchFunc(s.Largest, size, func(e EntrySize, size int) int {
+		if e.Size >= size {
+			return -1
+		}
+		return 1
+	})
+	if i < s.largest {
+		s.Largest = slices.Insert(s.Largest, i, EntrySize{DN: entry.DN, Size: size})
+		if len(s.Largest) > s.largest {
+			s.Largest = s.Largest[:s.largest]
+		}
+	}
+	return nil
+}
+
+// CollectStats returns the statistics of the records.
+func CollectStats(records iter.Seq2[*Entry, error], opts *StatsOptions) (*Stats, error) {
+	s := NewStats(opts)
+	for record, err := range records {
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Add(record); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// byCount returns the keys of the map, the largest count first.
+func byCount[K cmp.Ordered](m map[K]int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		if c := cmp.Compare(m[b], m[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return keys
+}
+
+func writeHistogram(w io.Writer, buckets []SizeBucket) {
+	for _, b := range buckets {
+		if b.Max == 0 {
+			fmt.Fprintf(w, "  > %d\t%d\n", sizeBounds[len(sizeBounds)-1], b.Count)
+			continue
+		}
+		fmt.Fprintf(w, "  <= %d\t%d\n", b.Max, b.Count)
+	}
+}
+
+// WriteText writes the statistics as a human readable report.
+func (s *Stats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "records\t%d\n", s.Records)
+	fmt.Fprintf(tw, "entries\t%d\n", s.Entries)
+	fmt.Fprintf(tw, "binary values\t%d\t(%d bytes)\n", s.BinaryValues, s.BinaryBytes)
+	if len(s.ChangeTypes) > 0 {
+		fmt.Fprintf(tw, "\nchange types\n")
+		for _, t := range byCount(s.ChangeTypes) {
+			fmt.Fprintf(tw, "  %s\t%d\n", t, s.ChangeTypes[t])
+		}
+	}
+	if len(s.ObjectClasses) > 0 {
+		fmt.Fprintf(tw, "\nobject classes\n")
+		for _, oc := range byCount(s.ObjectClasses) {
+			fmt.Fprintf(tw, "  %s\t%d\n", oc, s.ObjectClasses[oc])
+		}
+	}
+	if len(s.Depths) > 0 {
+		fmt.Fprintf(tw, "\ndepths\n")
+		depths := make([]int, 0, len(s.Depths))
+		for d := range s.Depths {
+			depths = append(depths, d)
+		}
+		slices.Sort(depths)
+		for _, d := range depths {
+			fmt.Fprintf(tw, "  %d\t%d\n", d, s.Depths[d])
+		}
+	}
+	if len(s.Attributes) > 0 {
+		fmt.Fprintf(tw, "\nattributes\tentries\tvalues\tbytes\tmax size\n")
+		entries := make(map[string]int, len(s.Attributes))
+		for name, a := range s.Attributes {
+			entries[name] = a.Entries
+		}
+		for _, name := range byCount(entries) {
+			a := s.Attributes[name]
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\n", name, a.Entries, a.Values, a.Bytes, a.MaxSize)
+		}
+		fmt.Fprintf(tw, "\nvalue sizes\n")
+		writeHistogram(tw, s.ValueSizes)
+	}
+	if len(s.Largest) > 0 {
+		fmt.Fprintf(tw, "\nlargest entries\n")
+		for _, e := range s.Largest {
+			fmt.Fprintf(tw, "  %d\t%s\n", e.Size, e.DN)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,96 @@
+package ldif_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+var statsLDIF = `dn: dc=example,dc=org
+objectClass: top
+objectClass: domain
+dc: example
+
+dn: uid=jdoe,dc=example,dc=org
+objectClass: top
+objectClass: inetOrgPerson
+uid: jdoe
+cn: John Doe
+description: ` + strings.Repeat("x", 100) + `
+jpegPhoto:: /9j/4AA=
+
+dn: uid=asmith,dc=example,dc=org
+changetype: add
+objectclass: TOP
+UID: asmith
+userCertificate;binary:: MIIB
+
+dn: uid=old,dc=example,dc=org
+changetype: delete
+
+dn: uid=jdoe,dc=example,dc=org
+changetype: modify
+replace: cn
+cn: Johnny Doe
+-
+`
+
+func TestStats(t *testing.T) {
+	s, err := ldif.CollectStats(ldif.UnmarshalEntries(strings.NewReader(statsLDIF), &ldif.LDIF{}), &ldif.StatsOptions{Largest: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Records != 5 || s.Entries != 3 {
+		t.Errorf("got %d records, %d entries", s.Records, s.Entries)
+	}
+	if s.ChangeTypes["add"] != 1 || s.ChangeTypes["delete"] != 1 || s.ChangeTypes["modify"] != 1 || len(s.ChangeTypes) != 3 {
+		t.Errorf("unexpected change types: %v", s.ChangeTypes)
+	}
+	if s.ObjectClasses["top"] != 3 || s.ObjectClasses["inetOrgPerson"] != 1 || len(s.ObjectClasses) != 3 {
+		t.Errorf("unexpected object classes: %v", s.ObjectClasses)
+	}
+	if s.Depths[2] != 1 || s.Depths[3] != 2 {
+		t.Errorf("unexpected depths: %v", s.Depths)
+	}
+	if uid := s.Attributes["uid"]; uid == nil || uid.Entries != 2 || uid.Values != 2 || uid.Bytes != 10 || uid.MaxSize != 6 {
+		t.Errorf("unexpected uid stats: %+v", uid)
+	}
+	if d := s.Attributes["description"]; d == nil || d.ValueSizes[2].Count != 1 {
+		t.Errorf("unexpected description stats: %+v", d)
+	}
+	if s.BinaryValues != 2 || s.BinaryBytes != 8 {
+		t.Errorf("got %d binary values with %d bytes", s.BinaryValues, s.BinaryBytes)
+	}
+	if len(s.Largest) != 2 || s.Largest[0].DN != "uid=jdoe,dc=example,dc=org" || s.Largest[0].Size < s.Largest[1].Size {
+		t.Errorf("unexpected largest entries: %v", s.Largest)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"changeTypes":{"add":1,"delete":1,"modify":1}`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	var text strings.Builder
+	if err := s.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"records        5\n", "  top            3\n", "  <= 256  "} {
+		if !strings.Contains(text.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, text.String())
+		}
+	}
+}
+
+func TestStatsInvalidDN(t *testing.T) {
+	s := ldif.NewStats(nil)
+	if err := s.Add(&ldif.Entry{Entry: ldap.NewEntry("invalid", nil)}); !errors.Is(err, ldif.ErrInvalidDN) {
+		t.Errorf("got %v", err)
+	}
+}
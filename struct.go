@@ -0,0 +1,267 @@
+package ldif
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// generalizedTimeLayouts are tried in order when decoding a GeneralizedTime
+// value (RFC 4517, section 3.3.13) into a time.Time field.
+var generalizedTimeLayouts = []string{
+	"20060102150405Z0700",
+	"20060102150405.999999999Z0700",
+	"200601021504Z0700",
+	"2006010215Z0700",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// MarshalStruct returns a content record for the given DN built from the
+// fields of the struct (or pointer to struct) v.
+//
+// Only fields with an `ldap:"attributeName"` tag are used, a tag of "-" skips
+// the field. Adding ",omitempty" to the tag skips zero values. A field tagged
+// `ldap:"dn"` is ignored when marshalling, the dn argument is used instead.
+//
+// Supported field types are string, []string, []byte, [][]byte, all signed
+// and unsigned integers, bool (as "TRUE" / "FALSE") and time.Time (as
+// GeneralizedTime in UTC).
+func MarshalStruct(dn string, v any) (*Entry, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("nil pointer passed to MarshalStruct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("MarshalStruct requires a struct, got %T", v)
+	}
+
+	entry := &ldap.Entry{DN: dn}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, omitEmpty, ok := structTag(field)
+		if !ok || name == "dn" {
+			continue
+		}
+		fv := rv.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		values, err := encodeField(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", field.Name, err)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(name, values))
+	}
+	return &Entry{Entry: entry}, nil
+}
+
+// UnmarshalStruct fills the tagged fields of the struct pointed to by v with
+// the attribute values of the given content record or add request. Attribute
+// names are matched case-insensitively. See MarshalStruct for the supported
+// tags and field types.
+func UnmarshalStruct(e *Entry, v any) error {
+	if e == nil {
+		return errors.New("nil entry passed to UnmarshalStruct")
+	}
+	var dn string
+	attrs := make(map[string][]string)
+	switch {
+	case e.Entry != nil:
+		dn = e.Entry.DN
+		for _, attr := range e.Entry.Attributes {
+			key := strings.ToLower(attr.Name)
+			attrs[key] = append(attrs[key], attr.Values...)
+		}
+	case e.Add != nil:
+		dn = e.Add.DN
+		for _, attr := range e.Add.Attributes {
+			key := strings.ToLower(attr.Type)
+			attrs[key] = append(attrs[key], attr.Vals...)
+		}
+	default:
+		return errors.New("UnmarshalStruct requires a content record or an add request")
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("UnmarshalStruct requires a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalStruct requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, _, ok := structTag(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		if name == "dn" {
+			if fv.Kind() != reflect.String {
+				return fmt.Errorf("field %s: dn field must be a string", field.Name)
+			}
+			fv.SetString(dn)
+			continue
+		}
+		values, found := attrs[strings.ToLower(name)]
+		if !found {
+			continue
+		}
+		if err := decodeField(fv, values); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func structTag(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	if field.PkgPath != "" { // unexported
+		return "", false, false
+	}
+	tag, found := field.Tag.Lookup("ldap")
+	if !found || tag == "" || tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	if parts[0] == "" {
+		return "", false, false
+	}
+	return parts[0], omitEmpty, true
+}
+
+func encodeField(fv reflect.Value) ([]string, error) {
+	if fv.Type() == timeType {
+		t := fv.Interface().(time.Time)
+		return []string{t.UTC().Format("20060102150405Z")}, nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return []string{fv.String()}, nil
+	case reflect.Bool:
+		if fv.Bool() {
+			return []string{"TRUE"}, nil
+		}
+		return []string{"FALSE"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(fv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(fv.Uint(), 10)}, nil
+	case reflect.Slice:
+		switch elem := fv.Type().Elem(); {
+		case elem.Kind() == reflect.Uint8: // []byte
+			if fv.IsNil() {
+				return nil, nil
+			}
+			return []string{string(fv.Bytes())}, nil
+		case elem.Kind() == reflect.String:
+			// fv may be a named type like "type Names []string"
+			values := make([]string, fv.Len())
+			for i := range values {
+				values[i] = fv.Index(i).String()
+			}
+			return values, nil
+		case elem.Kind() == reflect.Slice && elem.Elem().Kind() == reflect.Uint8: // [][]byte
+			values := make([]string, fv.Len())
+			for i := range values {
+				values[i] = string(fv.Index(i).Bytes())
+			}
+			return values, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", fv.Type())
+}
+
+func decodeField(fv reflect.Value, values []string) error {
+	if fv.Type() == timeType {
+		t, err := parseGeneralizedTime(values[0])
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(values[0])
+	case reflect.Bool:
+		switch strings.ToUpper(values[0]) {
+		case "TRUE":
+			fv.SetBool(true)
+		case "FALSE":
+			fv.SetBool(false)
+		default:
+			return fmt.Errorf("invalid boolean value %q", values[0])
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(values[0], 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(values[0], 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Slice:
+		switch elem := fv.Type().Elem(); {
+		case elem.Kind() == reflect.Uint8: // []byte
+			fv.SetBytes([]byte(values[0]))
+		case elem.Kind() == reflect.String:
+			// the slice and its elements may be named types like
+			// "type Roles []Role"
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for i, val := range values {
+				slice.Index(i).SetString(val)
+			}
+			fv.Set(slice)
+		case elem.Kind() == reflect.Slice && elem.Elem().Kind() == reflect.Uint8: // [][]byte
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for i, val := range values {
+				slice.Index(i).SetBytes([]byte(val))
+			}
+			fv.Set(slice)
+		default:
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func parseGeneralizedTime(val string) (time.Time, error) {
+	// RFC 4517 allows a comma as decimal separator for the fraction
+	val = strings.Replace(val, ",", ".", 1)
+	for _, layout := range generalizedTimeLayouts {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid GeneralizedTime value %q", val)
+}
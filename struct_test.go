@@ -0,0 +1,171 @@
+package ldif_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldif"
+)
+
+type testUser struct {
+	DN        string    `ldap:"dn"`
+	UID       string    `ldap:"uid"`
+	CN        []string  `ldap:"cn"`
+	UIDNumber int       `ldap:"uidNumber"`
+	Locked    bool      `ldap:"pwdLocked"`
+	Photo     []byte    `ldap:"jpegPhoto,omitempty"`
+	Created   time.Time `ldap:"createTimestamp"`
+	Note      string    `ldap:"description,omitempty"`
+	Ignored   string    `ldap:"-"`
+	Untagged  string
+}
+
+func TestMarshalStruct(t *testing.T) {
+	u := testUser{
+		UID:       "someone",
+		CN:        []string{"Someone", "Some One"},
+		UIDNumber: 1000,
+		Locked:    true,
+		Created:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ignored:   "x",
+		Untagged:  "y",
+	}
+	e, err := ldif.MarshalStruct("uid=someone,dc=example,dc=org", &u)
+	if err != nil {
+		t.Fatalf("MarshalStruct: %s", err)
+	}
+	l := &ldif.LDIF{Entries: []*ldif.Entry{e}}
+	got, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	expected := `dn: uid=someone,dc=example,dc=org
+uid: someone
+cn: Someone
+cn: Some One
+uidNumber: 1000
+pwdLocked: TRUE
+createTimestamp: 20200102030405Z
+
+`
+	if got != expected {
+		t.Errorf("wrong output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestUnmarshalStruct(t *testing.T) {
+	l, err := ldif.Parse(`dn: uid=someone,dc=example,dc=org
+UID: someone
+cn: Someone
+cn: Some One
+uidNumber: 1000
+pwdLocked: FALSE
+jpegPhoto:: AAEC
+createTimestamp: 20200102030405.5+0100
+`)
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	var u testUser
+	if err := ldif.UnmarshalStruct(l.Entries[0], &u); err != nil {
+		t.Fatalf("UnmarshalStruct: %s", err)
+	}
+	if u.DN != "uid=someone,dc=example,dc=org" {
+		t.Errorf("wrong dn: %q", u.DN)
+	}
+	if u.UID != "someone" {
+		t.Errorf("wrong uid: %q", u.UID)
+	}
+	if len(u.CN) != 2 || u.CN[1] != "Some One" {
+		t.Errorf("wrong cn: %#v", u.CN)
+	}
+	if u.UIDNumber != 1000 {
+		t.Errorf("wrong uidNumber: %d", u.UIDNumber)
+	}
+	if u.Locked {
+		t.Error("pwdLocked should be false")
+	}
+	if !bytes.Equal(u.Photo, []byte{0, 1, 2}) {
+		t.Errorf("wrong jpegPhoto: %v", u.Photo)
+	}
+	want := time.Date(2020, 1, 2, 2, 4, 5, 500000000, time.UTC)
+	if !u.Created.Equal(want) {
+		t.Errorf("wrong createTimestamp: %s, expected %s", u.Created, want)
+	}
+}
+
+func TestUnmarshalStructErrors(t *testing.T) {
+	l, err := ldif.Parse("dn: uid=someone,dc=example,dc=org\nuidNumber: abc\n")
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	var u testUser
+	if err := ldif.UnmarshalStruct(l.Entries[0], &u); err == nil {
+		t.Error("expected error for invalid integer")
+	}
+	if err := ldif.UnmarshalStruct(l.Entries[0], u); err == nil {
+		t.Error("expected error for non-pointer argument")
+	}
+}
+
+type names []string
+
+type role string
+
+type blob []byte
+
+type namedSlices struct {
+	CN    names    `ldap:"cn"`
+	Cert  [][]byte `ldap:"userCertificate"`
+	Roles []role   `ldap:"nsRoleDN"`
+	Keys  []blob   `ldap:"sshPublicKey"`
+}
+
+func TestStructNamedSlice(t *testing.T) {
+	e, err := ldif.MarshalStruct("cn=foo,dc=example,dc=org", namedSlices{CN: names{"foo", "Foo"}, Cert: [][]byte{{0x30, 0x82}}})
+	if err != nil {
+		t.Fatalf("MarshalStruct: %s", err)
+	}
+	if cn := e.Entry.GetAttributeValues("cn"); len(cn) != 2 || cn[1] != "Foo" {
+		t.Errorf("wrong cn: %v", cn)
+	}
+	var v namedSlices
+	if err := ldif.UnmarshalStruct(e, &v); err != nil {
+		t.Fatalf("UnmarshalStruct: %s", err)
+	}
+	if len(v.CN) != 2 || v.CN[0] != "foo" || len(v.Cert) != 1 || v.Cert[0][1] != 0x82 {
+		t.Errorf("wrong struct: %+v", v)
+	}
+
+	// named element types
+	in := namedSlices{Roles: []role{"cn=admins", "cn=users"}, Keys: []blob{{0x00, 0x01}}}
+	if e, err = ldif.MarshalStruct("cn=foo,dc=example,dc=org", in); err != nil {
+		t.Fatalf("MarshalStruct: %s", err)
+	}
+	v = namedSlices{}
+	if err := ldif.UnmarshalStruct(e, &v); err != nil {
+		t.Fatalf("UnmarshalStruct: %s", err)
+	}
+	if len(v.Roles) != 2 || v.Roles[1] != "cn=users" || len(v.Keys) != 1 || string(v.Keys[0]) != "\x00\x01" {
+		t.Errorf("wrong struct: %+v", v)
+	}
+
+	// unsupported element types are an error, not a panic
+	var ints struct {
+		UID []int `ldap:"uidNumber"`
+	}
+	if err := ldif.UnmarshalStruct(e, &ints); err != nil {
+		t.Fatalf("UnmarshalStruct without uidNumber: %s", err)
+	}
+	e.Entry.Attributes = append(e.Entry.Attributes, ldap.NewEntryAttribute("uidNumber", []string{"1"}))
+	if err := ldif.UnmarshalStruct(e, &ints); err == nil {
+		t.Error("[]int: no error")
+	}
+	if _, err := ldif.MarshalStruct("cn=foo", struct {
+		UID []int `ldap:"uidNumber"`
+	}{UID: []int{1}}); err == nil {
+		t.Error("MarshalStruct []int: no error")
+	}
+}
@@ -0,0 +1,380 @@
+package ldif
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// A Transform changes a record, it returns the changed record or nil to
+// drop it. It may change the record in place.
+type Transform func(*Entry) (*Entry, error)
+
+// TransformEntries yields the records of entries after passing them through
+// the transforms, in order. An error of a transform ends the iteration.
+func TransformEntries(entries iter.Seq2[*Entry, error], transforms ...Transform) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		for entry, err := range entries {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, t := range transforms {
+				if entry == nil {
+					break
+				}
+				if entry, err = t(entry); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+			if entry != nil && !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// setValues sets the values of the attribute, its ByteValues are kept in
+// sync if they are used.
+func setValues(a *ldap.EntryAttribute, values []string) {
+	a.Values = values
+	if a.ByteValues != nil {
+		a.ByteValues = make([][]byte, len(values))
+		for i, v := range values {
+			a.ByteValues[i] = []byte(v)
+		}
+	}
+}
+
+// matchAttr returns a function which reports whether an attribute has one
+// of the names, compared case-insensitively. The name "+" matches all
+// operational attributes (see IsOperational).
+func matchAttr(names ...string) func(string) bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+	}
+	return func(name string) bool {
+		return set[strings.ToLower(name)] || (set["+"] && IsOperational(name))
+	}
+}
+
+// RenameAttr renames the attribute from to in content, add and modify
+// records.
+func RenameAttr(from, to string) Transform {
+	match := matchAttr(from)
+	return func(e *Entry) (*Entry, error) {
+		switch {
+		case e.Entry != nil:
+			for _, a := range e.Entry.Attributes {
+				if match(a.Name) {
+					a.Name = to
+				}
+			}
+		case e.Add != nil:
+			for i, a := range e.Add.Attributes {
+				if match(a.Type) {
+					e.Add.Attributes[i].Type = to
+				}
+			}
+		case e.Modify != nil:
+			for i, c := range e.Modify.Changes {
+				if match(c.Modification.Type) {
+					e.Modify.Changes[i].Modification.Type = to
+				}
+			}
+		}
+		return e, nil
+	}
+}
+
+// DropAttr removes the attributes from content and add records and their
+// changes from modify records, modify records without other changes are
+// dropped. The name "+" drops all operational attributes.
+func DropAttr(names ...string) Transform {
+	match := matchAttr(names...)
+	return func(e *Entry) (*Entry, error) {
+		switch {
+		case e.Entry != nil:
+			e.Entry.Attributes = slices.DeleteFunc(e.Entry.Attributes, func(a *ldap.EntryAttribute) bool {
+				return match(a.Name)
+			})
+		case e.Add != nil:
+			e.Add.Attributes = slices.DeleteFunc(e.Add.Attributes, func(a ldap.Attribute) bool {
+				return match(a.Type)
+			})
+		case e.Modify != nil:
+			e.Modify.Changes = slices.DeleteFunc(e.Modify.Changes, func(c ldap.Change) bool {
+				return match(c.Modification.Type)
+			})
+			if len(e.Modify.Changes) == 0 {
+				// servers reject a modify without changes
+				return nil, nil
+			}
+		}
+		return e, nil
+	}
+}
+
+// SetAttr sets the values of the attribute in content and add records,
+// replacing the existing values.
+func SetAttr(name string, values ...string) Transform {
+	drop, add := DropAttr(name), AddValues(name, values...)
+	return func(e *Entry) (*Entry, error) {
+		if e.Entry == nil && e.Add == nil {
+			return e, nil
+		}
+		if _, err := drop(e); err != nil {
+			return nil, err
+		}
+		return add(e)
+	}
+}
+
+// AddValues adds the values to the attribute in content and add records,
+// if they are not there yet (e.g. an objectClass).
+func AddValues(name string, values ...string) Transform {
+	match := matchAttr(name)
+	return func(e *Entry) (*Entry, error) {
+		var existing []string
+		switch {
+		case e.Entry != nil:
+			existing = attributeValues(e.Entry, name)
+		case e.Add != nil:
+			for _, a := range e.Add.Attributes {
+				if match(a.Type) {
+					existing = append(existing, a.Vals...)
+				}
+			}
+		default:
+			return e, nil
+		}
+		var missing []string
+		for _, v := range values {
+			if !slices.Contains(existing, v) && !slices.Contains(missing, v) {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) == 0 {
+			return e, nil
+		}
+		if e.Entry != nil {
+			for _, a := range e.Entry.Attributes {
+				if match(a.Name) {
+					setValues(a, append(a.Values, missing...))
+					return e, nil
+				}
+			}
+			e.Entry.Attributes = append(e.Entry.Attributes, ldap.NewEntryAttribute(name, missing))
+			return e, nil
+		}
+		for i, a := range e.Add.Attributes {
+			if match(a.Type) {
+				e.Add.Attributes[i].Vals = append(a.Vals, missing...)
+				return e, nil
+			}
+		}
+		e.Add.Attribute(name, missing)
+		return e, nil
+	}
+}
+
+// MapValues replaces each value v of the attribute with f(v) in content,
+// add and modify records.
+func MapValues(name string, f func(string) string) Transform {
+	match := matchAttr(name)
+	mapAll := func(values []string) []string {
+		result := make([]string, len(values))
+		for i, v := range values {
+			result[i] = f(v)
+		}
+		return result
+	}
+	return func(e *Entry) (*Entry, error) {
+		switch {
+		case e.Entry != nil:
+			for _, a := range e.Entry.Attributes {
+				if match(a.Name) {
+					setValues(a, mapAll(a.Values))
+				}
+			}
+		case e.Add != nil:
+			for i, a := range e.Add.Attributes {
+				if match(a.Type) {
+					e.Add.Attributes[i].Vals = mapAll(a.Vals)
+				}
+			}
+		case e.Modify != nil:
+			for i, c := range e.Modify.Changes {
+				if match(c.Modification.Type) {
+					e.Modify.Changes[i].Modification.Vals = mapAll(c.Modification.Vals)
+				}
+			}
+		}
+		return e, nil
+	}
+}
+
+// FilterEntries drops the content and add records which do not match the
+// filter, other records are kept.
+func FilterEntries(filter *Filter) Transform {
+	return func(e *Entry) (*Entry, error) {
+		if entry := contentEntry(e); entry != nil && !filter.Match(entry) {
+			return nil, nil
+		}
+		return e, nil
+	}
+}
+
+// RewriteDN moves the records below the DN from to the DN to, like
+// LDIF.Rebase. An invalid from or to is returned as error of the first
+// record.
+func RewriteDN(from, to string, attributes []string) Transform {
+	r, err := newRebaser(from, to, attributes)
+	return func(e *Entry) (*Entry, error) {
+		if err != nil {
+			return nil, err
+		}
+		if err := r.entry(e); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+}
+
+// A TransformRule is the declarative form of one of the built-in
+// transforms, e.g. read from a JSON rule file with ParseTransformRules.
+// Op selects the transform, the other fields are its arguments:
+//
+//	rename:    Attribute, To
+//	drop:      Attributes
+//	set:       Attribute, Values
+//	add:       Attribute, Values
+//	map:       Attribute, Func ("lower", "upper" or "trim") and/or Table,
+//	           values which are not in the table are kept
+//	filter:    Filter
+//	rewriteDN: From, To, Attributes (DNAttributes if empty)
+type TransformRule struct {
+	Op         string
+	Attribute  string
+	Attributes []string
+	Values     []string
+	From       string
+	To         string
+	Func       string
+	Table      map[string]string
+	Filter     string
+}
+
+var valueFuncs = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// Transform returns the transform of the rule.
+func (r *TransformRule) Transform() (Transform, error) {
+	need := func(fields ...string) error {
+		for i := 0; i < len(fields); i += 2 {
+			if fields[i+1] == "" {
+				return fmt.Errorf("transform %q requires %s", r.Op, fields[i])
+			}
+		}
+		return nil
+	}
+	switch strings.ToLower(r.Op) {
+	case "rename":
+		if err := need("attribute", r.Attribute, "to", r.To); err != nil {
+			return nil, err
+		}
+		return RenameAttr(r.Attribute, r.To), nil
+	case "drop":
+		if len(r.Attributes) == 0 {
+			return nil, fmt.Errorf("transform %q requires attributes", r.Op)
+		}
+		return DropAttr(r.Attributes...), nil
+	case "set", "add":
+		if err := need("attribute", r.Attribute); err != nil {
+			return nil, err
+		}
+		if len(r.Values) == 0 {
+			return nil, fmt.Errorf("transform %q requires values", r.Op)
+		}
+		if strings.EqualFold(r.Op, "set") {
+			return SetAttr(r.Attribute, r.Values...), nil
+		}
+		return AddValues(r.Attribute, r.Values...), nil
+	case "map":
+		if err := need("attribute", r.Attribute); err != nil {
+			return nil, err
+		}
+		f, ok := valueFuncs[r.Func]
+		switch {
+		case r.Func == "" && r.Table == nil:
+			return nil, fmt.Errorf("transform %q requires func or table", r.Op)
+		case r.Func == "":
+			f = func(v string) string { return v }
+		case !ok:
+			return nil, fmt.Errorf("unknown func %q, must be lower, upper or trim", r.Func)
+		}
+		table := r.Table
+		return MapValues(r.Attribute, func(v string) string {
+			v = f(v)
+			if mapped, ok := table[v]; ok {
+				return mapped
+			}
+			return v
+		}), nil
+	case "filter":
+		filter, err := NewFilter(r.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %s", r.Filter, err)
+		}
+		return FilterEntries(filter), nil
+	case "rewritedn":
+		if err := need("from", r.From); err != nil {
+			return nil, err
+		}
+		if _, err := newRebaser(r.From, r.To, nil); err != nil {
+			return nil, err
+		}
+		attributes := r.Attributes
+		if len(attributes) == 0 {
+			attributes = nil
+		}
+		return RewriteDN(r.From, r.To, attributes), nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", r.Op)
+}
+
+// ParseTransformRules reads a JSON array of TransformRule and returns their
+// transforms, e.g.
+//
+//	[
+//	  {"op": "drop", "attributes": ["+"]},
+//	  {"op": "add", "attribute": "objectClass", "values": ["extensibleObject"]},
+//	  {"op": "map", "attribute": "mail", "func": "lower"}
+//	]
+func ParseTransformRules(data []byte) ([]Transform, error) {
+	var rules []*TransformRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	transforms := make([]Transform, len(rules))
+	for i, r := range rules {
+		if r == nil {
+			return nil, fmt.Errorf("rule %d: null rule", i+1)
+		}
+		t, err := r.Transform()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		transforms[i] = t
+	}
+	return transforms, nil
+}
@@ -0,0 +1,128 @@
+package ldif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldif"
+)
+
+var transformLDIF = `dn: uid=jdoe,ou=People,dc=old,dc=com
+objectClass: person
+uid: jdoe
+mail: JDoe@Example.org
+departmentNumber: 1
+manager: uid=boss,ou=People,dc=old,dc=com
+createTimestamp: 20240101000000Z
+
+dn: cn=printer,ou=Devices,dc=old,dc=com
+objectClass: device
+cn: printer
+`
+
+func transform(t *testing.T, in string, transforms ...ldif.Transform) string {
+	t.Helper()
+	l := &ldif.LDIF{}
+	for entry, err := range ldif.TransformEntries(ldif.UnmarshalEntries(strings.NewReader(in), &ldif.LDIF{}), transforms...) {
+		if err != nil {
+			t.Fatalf("transform: %s", err)
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+	out, err := ldif.Marshal(l)
+	if err != nil {
+		t.Fatalf("marshal: %s", err)
+	}
+	return out
+}
+
+var transformedLDIF = `dn: uid=jdoe,ou=People,dc=new,dc=org
+departmentNumber: Sales
+email: jdoe@example.org
+manager: uid=boss,ou=People,dc=new,dc=org
+objectClass: person
+objectClass: extensibleObject
+uid: jdoe
+o: Example
+
+`
+
+func TestTransformEntries(t *testing.T) {
+	filter, err := ldif.NewFilter("(objectClass=person)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := transform(t, transformLDIF,
+		ldif.FilterEntries(filter),
+		ldif.DropAttr("+"),
+		ldif.RenameAttr("MAIL", "email"),
+		ldif.MapValues("email", strings.ToLower),
+		ldif.MapValues("departmentNumber", func(v string) string { return map[string]string{"1": "Sales"}[v] }),
+		ldif.AddValues("objectClass", "person", "extensibleObject"),
+		ldif.SetAttr("o", "Example"),
+		ldif.RewriteDN("dc=old,dc=com", "dc=new,dc=org", nil),
+	)
+	if out != transformedLDIF {
+		t.Errorf("got:\n%s\nexpected:\n%s", out, transformedLDIF)
+	}
+}
+
+func TestTransformChangeRecords(t *testing.T) {
+	in := "dn: uid=jdoe,dc=old,dc=com\nchangetype: modify\nreplace: mail\nmail: JDoe@Example.org\n-\ndelete: modifyTimestamp\n-\n\n" +
+		"dn: uid=asmith,dc=old,dc=com\nchangetype: delete\n\n" +
+		// dropped, as no change remains
+		"dn: uid=bjones,dc=old,dc=com\nchangetype: modify\nreplace: modifyTimestamp\nmodifyTimestamp: 20240101000000Z\n-\n"
+	expected := "dn: uid=jdoe,dc=old,dc=com\nchangetype: modify\nreplace: email\nemail: jdoe@example.org\n-\n\n" +
+		"dn: uid=asmith,dc=old,dc=com\nchangetype: delete\n\n"
+	out := transform(t, in,
+		ldif.DropAttr("+"),
+		ldif.RenameAttr("mail", "email"),
+		ldif.MapValues("email", strings.ToLower),
+		ldif.SetAttr("mail", "ignored"),
+	)
+	if out != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	for _, err := range ldif.TransformEntries(ldif.UnmarshalEntries(strings.NewReader(in), &ldif.LDIF{}), ldif.RewriteDN("dc=old,,", "dc=new", nil)) {
+		if err == nil {
+			t.Error("invalid DN: expected an error")
+		}
+		break
+	}
+}
+
+func TestParseTransformRules(t *testing.T) {
+	rules := `[
+	  {"op": "filter", "filter": "(objectClass=person)"},
+	  {"op": "drop", "attributes": ["+"]},
+	  {"op": "rename", "attribute": "mail", "to": "email"},
+	  {"op": "map", "attribute": "email", "func": "lower"},
+	  {"op": "map", "attribute": "departmentNumber", "table": {"1": "Sales"}},
+	  {"op": "add", "attribute": "objectClass", "values": ["extensibleObject"]},
+	  {"op": "set", "attribute": "o", "values": ["Example"]},
+	  {"op": "rewriteDN", "from": "dc=old,dc=com", "to": "dc=new,dc=org"}
+	]`
+	transforms, err := ldif.ParseTransformRules([]byte(rules))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if out := transform(t, transformLDIF, transforms...); out != transformedLDIF {
+		t.Errorf("got:\n%s\nexpected:\n%s", out, transformedLDIF)
+	}
+
+	for _, rules := range []string{
+		`{"op": "drop"}`,
+		`[{"op": "nope"}]`,
+		`[{"op": "drop"}]`,
+		`[{"op": "rename", "attribute": "mail"}]`,
+		`[{"op": "map", "attribute": "mail", "func": "reverse"}]`,
+		`[{"op": "filter", "filter": "objectClass=*"}]`,
+		`[{"op": "rewriteDN", "from": "dc=old,,"}]`,
+		`[null]`,
+	} {
+		if _, err := ldif.ParseTransformRules([]byte(rules)); err == nil {
+			t.Errorf("%s: expected an error", rules)
+		}
+	}
+}